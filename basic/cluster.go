@@ -0,0 +1,155 @@
+package basic
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+	"github.com/duyhai-bic/rds_exporter/sessions"
+)
+
+var (
+	clusterDimensions = []config.Dimension{{Name: "DBClusterIdentifier"}}
+	roleDimensions    = []config.Dimension{{Name: "DBClusterIdentifier"}, {Name: "Role"}}
+)
+
+// ClusterMetrics are Aurora cluster-level metrics queried once per cluster.
+var ClusterMetrics = []Metric{
+	{
+		cwName:         "AuroraGlobalDBReplicationLag",
+		prometheusName: "aws_rds_aurora_global_db_replication_lag_average",
+		prometheusHelp: "For an Aurora global database, the amount of lag when replicating updates from the primary AWS Region. Units: Milliseconds",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "BackupRetentionPeriodStorageUsed",
+		prometheusName: "aws_rds_backup_retention_period_storage_used_average",
+		prometheusHelp: "The total amount of backup storage used to support the point-in-time restore feature within the backup retention window. Units: Bytes",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "SnapshotStorageUsed",
+		prometheusName: "aws_rds_snapshot_storage_used_average",
+		prometheusHelp: "The total amount of backup storage consumed by all snapshots outside the backup retention window. Units: Bytes",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "TotalBackupStorageBilled",
+		prometheusName: "aws_rds_total_backup_storage_billed_average",
+		prometheusHelp: "The total amount of backup storage for which you are billed. Units: Bytes",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "VolumeBytesUsed",
+		prometheusName: "aws_rds_volume_bytes_used_average",
+		prometheusHelp: "The amount of storage used by the Aurora cluster. Units: Bytes",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "VolumeReadIOPs",
+		prometheusName: "aws_rds_volume_read_io_ps_average",
+		prometheusHelp: "The number of billed read I/O operations from the cluster volume within a 5-minute interval. Units: Count",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "VolumeWriteIOPs",
+		prometheusName: "aws_rds_volume_write_io_ps_average",
+		prometheusHelp: "The number of write disk I/O operations to the cluster volume within a 5-minute interval. Units: Count",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "ServerlessDatabaseCapacity",
+		prometheusName: "aws_rds_cluster_serverless_database_capacity_average",
+		prometheusHelp: "The current capacity of Aurora Serverless v2 instances of the cluster, in Aurora capacity units (ACUs). Units: Count",
+		dimensions:     clusterDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "CPUUtilization",
+		prometheusName: "aws_rds_cluster_cpu_utilization_average",
+		prometheusHelp: "The percentage of CPU utilization of the cluster instances with given role. Units: Percent",
+		dimensions:     roleDimensions,
+		engines:        auroraEngines,
+	},
+	{
+		cwName:         "DatabaseConnections",
+		prometheusName: "aws_rds_cluster_database_connections_average",
+		prometheusHelp: "The number of client network connections to the cluster instances with given role. Units: Count",
+		dimensions:     roleDimensions,
+		engines:        auroraEngines,
+	},
+}
+
+// roles are values of Role dimension of cluster metrics.
+var roles = []string{"WRITER", "READER"}
+
+// clusterScoped returns true if metric is queried once per cluster.
+func (m Metric) clusterScoped() bool {
+	for _, d := range m.dimensions {
+		if d.Name == "DBClusterIdentifier" && d.Value == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// roles returns Role dimension values metric is queried for, or a single empty string if it has no such dimension.
+func (m Metric) roles() []string {
+	for _, d := range m.dimensions {
+		if d.Name == "Role" && d.Value == "" {
+			return roles
+		}
+	}
+	return []string{""}
+}
+
+// clusters returns the first instance of each cluster of given instances.
+func clusters(instances []sessions.Instance) []*sessions.Instance {
+	var res []*sessions.Instance
+	seen := make(map[string]struct{})
+	for i := range instances {
+		instance := &instances[i]
+		if instance.Cluster == "" {
+			continue
+		}
+		key := clusterCacheKey(instance)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		res = append(res, instance)
+	}
+	return res
+}
+
+// clusterCacheKey returns cache key for the cluster of given instance.
+// It can't collide with instance cache keys as identifiers can't contain slashes.
+func clusterCacheKey(instance *sessions.Instance) string {
+	return instance.Region + "/" + instance.AccountID + "/cluster/" + instance.Cluster
+}
+
+// clusterTarget returns target for the cluster of given instance and given role (may be empty).
+func clusterTarget(instance *sessions.Instance, role string) *target {
+	labels := prometheus.Labels{
+		"region":             instance.Region,
+		"cluster_identifier": instance.Cluster,
+	}
+	if role != "" {
+		labels["role"] = strings.ToLower(role)
+	}
+	return &target{
+		key:     clusterCacheKey(instance),
+		labels:  withMetricLabels(labels, instance),
+		cluster: instance.Cluster,
+		role:    role,
+		engine:  instance.Engine,
+	}
+}
@@ -1,95 +1,471 @@
 package basic
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/duyhai-bic/rds_exporter/config"
 	"github.com/duyhai-bic/rds_exporter/sessions"
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
 //go:generate go run generate/main.go generate/utils.go
 
+const (
+	scrapeTimeName = "rds_exporter_scrape_duration_seconds"
+	scrapeTimeHelp = "Time this RDS scrape took, in seconds."
+
+	cacheAgeName = "rds_exporter_basic_cache_age_seconds"
+	cacheAgeHelp = "Time since basic metrics of the instance were refreshed, in seconds."
+
+	clusterCacheAgeName = "rds_exporter_basic_cluster_cache_age_seconds"
+	clusterCacheAgeHelp = "Time since basic metrics of the cluster were refreshed, in seconds."
+)
+
 var (
-	scrapeTimeDesc = prometheus.NewDesc(
-		"rds_exporter_scrape_duration_seconds",
-		"Time this RDS scrape took, in seconds.",
-		[]string{},
-		nil,
-	)
+	scrapeTimeDesc = prometheus.NewDesc(scrapeTimeName, scrapeTimeHelp, []string{}, nil)
+
+	// ScrapeDuration tracks durations of basic metrics scrapes.
+	ScrapeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                        "rds_exporter_basic_scrape_duration_seconds",
+		Help:                        "Basic metrics scrape cycle duration, in seconds.",
+		Buckets:                     prometheus.ExponentialBuckets(0.1, 2, 10),
+		NativeHistogramBucketFactor: 1.1,
+	})
+
+	// EmptyResults counts basic metrics queries without datapoints.
+	EmptyResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rds_exporter_basic_empty_results_total",
+		Help: "Number of basic metrics queries that returned no datapoints, by instance engine and CloudWatch metric.",
+	}, []string{"engine", "metric"})
 )
 
+// Metric represents a single CloudWatch metric.
+// Only cwName, prometheusName and prometheusHelp are set for built-in metrics; defaults are used for other fields.
 type Metric struct {
 	cwName         string
 	prometheusName string
 	prometheusHelp string
+
+	namespace  string             // AWS/RDS if empty
+	dimensions []config.Dimension // DBInstanceIdentifier if empty
+	statistics []string           // Average if empty
+	period     time.Duration      // Period if zero
+	unit       string
+	engines    []string // engine patterns; all engines if empty
 }
 
+// series returns CloudWatch statistics and Prometheus metric names for them.
+func (m Metric) series(statisticLabel bool) ([]string, []string) {
+	statistics := m.statistics
+	if len(statistics) == 0 {
+		statistics = []string{"Average"}
+	}
+	names := config.Metric{
+		PrometheusName: m.prometheusName,
+		Statistics:     statistics,
+	}.PrometheusNames(statisticLabel)
+	return statistics, names
+}
+
+// metricsFromConfig returns metrics from catalog file, or built-in instance and cluster metrics.
+func metricsFromConfig(config *config.Config) []Metric {
+	if config == nil || len(config.Basic.Metrics) == 0 {
+		res := make([]Metric, 0, len(Metrics)+len(ClusterMetrics))
+		return append(append(res, Metrics...), ClusterMetrics...)
+	}
+
+	res := make([]Metric, len(config.Basic.Metrics))
+	for i, m := range config.Basic.Metrics {
+		res[i] = Metric{
+			cwName:         m.Name,
+			statistics:     m.Statistics,
+			prometheusName: m.PrometheusName,
+			prometheusHelp: m.PrometheusHelp,
+			namespace:      m.Namespace,
+			dimensions:     m.Dimensions,
+			period:         m.Period,
+			unit:           m.Unit,
+			engines:        m.Engines,
+		}
+		if res[i].prometheusHelp == "" {
+			res[i].prometheusHelp = m.Name
+		}
+	}
+	return res
+}
+
+// MaxCacheAge is the maximal age of cached metrics; older metrics are not exposed.
+const MaxCacheAge = 10 * time.Minute
+
+// cacheEntry contains the latest scraped metrics of a single instance or cluster.
+type cacheEntry struct {
+	region    string
+	accountID string // empty if not resolved or account_id label is not requested
+	instance  string // empty for cluster
+	cluster   string // empty for instance
+	metrics   []prometheus.Metric
+	capacity  *float64 // the latest Serverless v2 instance capacity; nil if unknown
+	updated   time.Time
+}
+
+// Collector collects basic RDS metrics in background and serves them from cache.
 type Collector struct {
-	config   *config.Config
-	sessions *sessions.Sessions
-	metrics  []Metric
-	l        log.Logger
+	rw           sync.RWMutex
+	config       *config.Config
+	sessions     *sessions.Sessions
+	metrics      []Metric
+	discovered   map[string][]Metric // nil if discovery is disabled or was not done yet
+	discoveredAt time.Time           // time of the last discovery; zero if discovery should be done on the next refresh
+
+	pool       *Pool
+	transforms *transform.Rules
+	cancel     context.CancelFunc // stops background refresh
+	l          log.Logger
+
+	cacheRW      sync.RWMutex
+	cache        map[string]cacheEntry // region/account/instance => entry
+	lastDuration time.Duration
 }
 
-// New creates a new instance of a Collector.
-func New(config *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
-	return &Collector{
-		config:   config,
-		sessions: sessions,
-		metrics:  Metrics,
-		l:        log.With(logger, "component", "basic"),
+// New creates a new instance of a Collector, performs the first scrape synchronously,
+// and starts refreshing metrics in background every Period.
+// Transformation rules are read from configuration only once.
+func New(cfg *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
+	workers := config.DefaultBasic.Workers
+	var transforms []config.Transform
+	if cfg != nil {
+		workers = cfg.Basic.Workers
+		transforms = cfg.Transforms
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Collector{
+		config:     cfg,
+		sessions:   sessions,
+		metrics:    metricsFromConfig(cfg),
+		pool:       NewPool(ctx, workers),
+		transforms: transform.New(transforms),
+		cancel:     cancel,
+		l:          log.With(logger, "component", "basic"),
+		cache:      make(map[string]cacheEntry),
 	}
+
+	e.refresh(ctx)
+	go RunEveryPeriod(ctx, e.refresh)
+	return e
+}
+
+// Stop stops refreshing metrics in background and pool workers; the last cached metrics are still served.
+func (e *Collector) Stop() {
+	e.cancel()
 }
 
+// Update updates configuration, sessions, and the number of workers.
+// Metrics are rediscovered in background every discovery interval, not on update.
 func (e *Collector) Update(config *config.Config, sessions *sessions.Sessions) {
+	metrics := metricsFromConfig(config)
+	if config != nil {
+		e.pool.Resize(config.Basic.Workers)
+	}
+
+	e.rw.Lock()
 	e.config = config
 	e.sessions = sessions
+	e.metrics = metrics
+	e.rw.Unlock()
 }
 
-func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
-	// unchecked collector
+// discover rediscovers metrics of all instances if discovery is enabled and the last discovery is older than
+// discovery interval, and returns discovered metrics, or nil if discovery is disabled.
+func (e *Collector) discover(ctx context.Context, sessions *sessions.Sessions, metrics []Metric, opts config.Basic) map[string][]Metric {
+	if !opts.Discovery {
+		return nil
+	}
+
+	e.rw.RLock()
+	discovered, discoveredAt := e.discovered, e.discoveredAt
+	e.rw.RUnlock()
+	if !discoveredAt.IsZero() && time.Since(discoveredAt) < opts.DiscoveryInterval {
+		return discovered
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.ScrapeTimeout)
+	defer cancel()
+	discovered = discover(ctx, sessions.AllSessions(), metrics, e.l)
+
+	e.rw.Lock()
+	e.discovered, e.discoveredAt = discovered, time.Now()
+	e.rw.Unlock()
+	return discovered
 }
 
-func (e *Collector) Collect(ch chan<- prometheus.Metric) {
-	now := time.Now()
-	e.collect(ch)
+// RunEveryPeriod calls refresh at the start of every CloudWatch period until context is canceled.
+func RunEveryPeriod(ctx context.Context, refresh func(ctx context.Context)) {
+	for {
+		t := time.NewTimer(time.Until(time.Now().Truncate(Period).Add(Period)))
+		select {
+		case <-t.C:
+			// nothing
+		case <-ctx.Done():
+			t.Stop()
+			return
+		}
 
-	// Collect scrape time
-	ch <- prometheus.MustNewConstMetric(scrapeTimeDesc, prometheus.GaugeValue, time.Since(now).Seconds())
+		refresh(ctx)
+	}
 }
 
-func (e *Collector) collect(ch chan<- prometheus.Metric) {
+// refresh scrapes metrics of all instances within scrape timeout and updates cache.
+// Metrics are rediscovered first if needed; that time is not included in scrape duration.
+func (e *Collector) refresh(ctx context.Context) {
+	e.rw.RLock()
+	sess, metrics := e.sessions, e.metrics
+	opts := config.DefaultBasic
+	if e.config != nil {
+		opts = e.config.Basic
+	}
+	e.rw.RUnlock()
+
+	discovered := e.discover(ctx, sess, metrics, opts)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, opts.ScrapeTimeout)
+	defer cancel()
+
+	var m sync.Mutex
+	results := make(map[string][]prometheus.Metric) // region/account/instance => metrics
+	capacities := make(map[string]float64)          // region/account/instance => ACUs
+	current := make(map[string]struct{})            // cache keys of all enabled instances and their clusters
+
 	var wg sync.WaitGroup
-	defer wg.Wait()
-	for _, instances := range e.sessions.AllSessions() {
+	for session, instances := range sess.AllSessions() {
+		enabledInstances := make([]sessions.Instance, 0, len(instances))
 		for _, instance := range instances {
 			if instance.DisableBasicMetrics {
 				level.Debug(e.l).Log("msg", fmt.Sprintf("Instance %s has disabled basic metrics, skipping.", instance))
 				continue
 			}
-			instance := instance
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-
-				s := NewScraper(&instance, e, ch)
-				if s == nil {
-					level.Error(e.l).Log("msg", fmt.Sprintf("No scraper for %s, skipping.", instance))
-					return
+			enabledInstances = append(enabledInstances, instance)
+			current[cacheKey(&instance)] = struct{}{}
+			if instance.Cluster != "" {
+				current[clusterCacheKey(&instance)] = struct{}{}
+			}
+		}
+		if len(enabledInstances) == 0 {
+			continue
+		}
+
+		s := newScraper(session, enabledInstances, metrics, discovered, opts, e.pool, e.l)
+		s.transforms = e.transforms
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := s.scrape(ctx)
+			m.Lock()
+			for key, metrics := range res {
+				results[key] = metrics
+			}
+			for key, capacity := range s.capacities {
+				capacities[key] = capacity
+			}
+			m.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	duration := time.Since(start)
+	ScrapeDuration.Observe(duration.Seconds())
+
+	now := time.Now()
+	e.cacheRW.Lock()
+	defer e.cacheRW.Unlock()
+
+	e.lastDuration = duration
+	for _, instances := range sess.AllSessions() {
+		for i := range instances {
+			instance := &instances[i]
+			if metrics, ok := results[cacheKey(instance)]; ok {
+				entry := cacheEntry{
+					region:    instance.Region,
+					accountID: accountIDLabel(instance),
+					instance:  instance.Instance,
+					metrics:   metrics,
+					updated:   now,
+				}
+				if capacity, ok := capacities[cacheKey(instance)]; ok {
+					entry.capacity = &capacity
+				}
+				e.cache[cacheKey(instance)] = entry
+			}
+			if instance.Cluster == "" {
+				continue
+			}
+			if metrics, ok := results[clusterCacheKey(instance)]; ok {
+				e.cache[clusterCacheKey(instance)] = cacheEntry{
+					region:    instance.Region,
+					accountID: accountIDLabel(instance),
+					cluster:   instance.Cluster,
+					metrics:   metrics,
+					updated:   now,
 				}
-				s.Scrape()
-			}()
+			}
+		}
+	}
+
+	// keep metrics of instances that were not scraped successfully this time, but remove old and removed ones
+	for key, entry := range e.cache {
+		if _, ok := current[key]; !ok || now.Sub(entry.updated) > MaxCacheAge {
+			delete(e.cache, key)
+		}
+	}
+}
+
+// cacheKey returns cache key for given instance.
+// It includes account ID, so instances with the same identifier in different accounts do not collide.
+func cacheKey(instance *sessions.Instance) string {
+	return instance.Region + "/" + instance.AccountID + "/" + instance.Instance
+}
+
+// accountIDLabel returns account ID of given instance if it should be added to metrics, or empty string.
+func accountIDLabel(instance *sessions.Instance) string {
+	if !instance.AccountIDLabel {
+		return ""
+	}
+	return instance.AccountID
+}
+
+// cacheAgeMetric returns age of given instance or cluster cache entry.
+func cacheAgeMetric(entry *cacheEntry, age float64) prometheus.Metric {
+	name, help := cacheAgeName, cacheAgeHelp
+	labels := prometheus.Labels{"region": entry.region, "instance": entry.instance}
+	if entry.cluster != "" {
+		name, help = clusterCacheAgeName, clusterCacheAgeHelp
+		labels = prometheus.Labels{"region": entry.region, "cluster_identifier": entry.cluster}
+	}
+	if entry.accountID != "" {
+		labels["account_id"] = entry.accountID
+	}
+
+	m := prometheus.MustNewConstMetric(prometheus.NewDesc(name, help, nil, labels), prometheus.GaugeValue, age)
+	return namedMetric{Metric: m, name: name, help: help}
+}
+
+func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
+	// unchecked collector
+}
+
+// Collect implements prometheus.Collector: it sends cached metrics without making any AWS API requests.
+func (e *Collector) Collect(ch chan<- prometheus.Metric) {
+	e.cacheRW.RLock()
+	defer e.cacheRW.RUnlock()
+
+	now := time.Now()
+	for _, entry := range e.cache {
+		for _, m := range entry.metrics {
+			ch <- m
+		}
+		ch <- cacheAgeMetric(&entry, now.Sub(entry.updated).Seconds())
+	}
+
+	// Collect the last refresh time
+	scrapeTime := prometheus.MustNewConstMetric(scrapeTimeDesc, prometheus.GaugeValue, e.lastDuration.Seconds())
+	ch <- namedMetric{Metric: scrapeTime, name: scrapeTimeName, help: scrapeTimeHelp}
+}
+
+// Gatherer returns a gatherer of basic metrics that is chosen on every call from the current configuration:
+// the Collector itself if all datapoints are exposed, as a registry rejects several datapoints of the same series,
+// or a registry that checks metrics consistency otherwise.
+func (e *Collector) Gatherer() prometheus.Gatherer {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		e.rw.RLock()
+		allDatapoints := e.config != nil && e.config.Basic.AllDatapoints
+		e.rw.RUnlock()
+
+		if allDatapoints {
+			return e.Gather()
 		}
+		return registry.Gather()
+	})
+}
+
+// Gather implements prometheus.Gatherer. Unlike prometheus.Registry, it does not reject duplicate series,
+// so all datapoints of a single series can be exposed with their timestamps.
+func (e *Collector) Gather() ([]*dto.MetricFamily, error) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(ch)
+		close(ch)
+	}()
+
+	var err error
+	families := make(map[string]*dto.MetricFamily)
+	for m := range ch {
+		nm, ok := m.(namedMetric)
+		if !ok {
+			err = fmt.Errorf("metric %s can't be gathered", m.Desc())
+			continue
+		}
+		pb := new(dto.Metric)
+		if e := nm.Write(pb); e != nil {
+			err = e
+			continue
+		}
+
+		mf := families[nm.name]
+		if mf == nil {
+			name, help := nm.name, nm.help
+			mf = &dto.MetricFamily{
+				Name: &name,
+				Help: &help,
+				Type: dto.MetricType_GAUGE.Enum(),
+			}
+			if nm.counter {
+				mf.Type = dto.MetricType_COUNTER.Enum()
+			}
+			families[nm.name] = mf
+		}
+		mf.Metric = append(mf.Metric, pb)
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		sort.SliceStable(mf.Metric, func(i, j int) bool {
+			li, lj := labelsString(mf.Metric[i]), labelsString(mf.Metric[j])
+			if li != lj {
+				return li < lj
+			}
+			return mf.Metric[i].GetTimestampMs() < mf.Metric[j].GetTimestampMs()
+		})
+		res = append(res, mf)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res, err
+}
+
+// labelsString returns metric labels as a string for sorting.
+func labelsString(m *dto.Metric) string {
+	pairs := make([]string, len(m.Label))
+	for i, l := range m.Label {
+		pairs[i] = l.GetName() + "=" + l.GetValue()
 	}
+	return strings.Join(pairs, ",")
 }
 
 // check interfaces
 var (
 	_ prometheus.Collector = (*Collector)(nil)
+	_ prometheus.Gatherer  = (*Collector)(nil)
 )
@@ -20,11 +20,13 @@ func TestCollector(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	client, err := client.New(logger, client.Options{})
+	require.NoError(t, err)
+	sess, err := sessions.New(cfg.Instances, client, logger, sessions.TraceOptions{})
 	require.NoError(t, err)
 
 	c := New(cfg, sess, logger)
+	defer c.Stop()
 
 	actualMetrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
 	sort.Slice(actualMetrics, func(i, j int) bool { return actualMetrics[i].Less(actualMetrics[j]) })
@@ -55,7 +57,8 @@ func TestCollectorDisableBasicMetrics(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
+	client, err := client.New(logger, client.Options{})
+	require.NoError(t, err)
 	instanceGroups := make(map[bool][]string, 2)
 	for i := range cfg.Instances {
 		// Disable basic metrics in even instances.
@@ -65,10 +68,11 @@ func TestCollectorDisableBasicMetrics(t *testing.T) {
 		// Groups instance names by disabled or enabled metrics.
 		instanceGroups[isDisabled] = append(instanceGroups[isDisabled], cfg.Instances[i].Instance)
 	}
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, client, logger, sessions.TraceOptions{})
 	require.NoError(t, err)
 
 	c := New(cfg, sess, logger)
+	defer c.Stop()
 
 	actualMetrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
 	actualLines := helpers.Format(helpers.WriteMetrics(actualMetrics))
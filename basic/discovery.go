@@ -0,0 +1,107 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/duyhai-bic/rds_exporter/client"
+	"github.com/duyhai-bic/rds_exporter/config"
+	"github.com/duyhai-bic/rds_exporter/sessions"
+)
+
+// recentlyActive limits discovered metrics to ones with datapoints in the past three hours,
+// the only value supported by ListMetrics.
+const recentlyActive = "PT3H"
+
+// discoverable returns true if metric is AWS/RDS metric with default DBInstanceIdentifier dimension,
+// so it is requested only if it is discovered for instance.
+func (m Metric) discoverable() bool {
+	return (m.namespace == "" || m.namespace == "AWS/RDS") && len(m.dimensions) == 0
+}
+
+// discover returns discovered metrics of all instances keyed by instance cache key.
+// Instances of sessions with ListMetrics errors and instances without recently active metrics (new or idle ones)
+// are not included, so all configured metrics are requested for them.
+func discover(ctx context.Context, all map[*session.Session][]sessions.Instance, metrics []Metric, logger log.Logger) map[string][]Metric {
+	res := make(map[string][]Metric)
+	for session, instances := range all {
+		found, err := listMetrics(ctx, session)
+		if err != nil {
+			level.Error(logger).Log("msg", "Failed to discover metrics.", "error", err)
+			continue
+		}
+
+		for i := range instances {
+			instance := &instances[i]
+			if len(found[instance.Instance]) == 0 {
+				level.Debug(logger).Log("msg", fmt.Sprintf("No recently active metrics of %s.", instance))
+				continue
+			}
+
+			discovered := discoveredMetrics(found[instance.Instance], metrics, logger)
+			level.Debug(logger).Log("msg", fmt.Sprintf("Discovered %d metrics of %s.", len(discovered), instance))
+			res[cacheKey(instance)] = discovered
+		}
+	}
+	return res
+}
+
+// discoveredMetrics returns metrics for given CloudWatch names: configured metrics if they are known,
+// or metrics with default Prometheus names. Metrics with names used by configured metrics are skipped.
+func discoveredMetrics(cwNames []string, metrics []Metric, logger log.Logger) []Metric {
+	known := make(map[string][]Metric) // CloudWatch name => configured metrics
+	names := make(map[string]struct{}) // Prometheus names of configured metrics
+	for _, m := range metrics {
+		if m.discoverable() {
+			known[m.cwName] = append(known[m.cwName], m)
+		}
+		names[m.prometheusName] = struct{}{}
+	}
+
+	var res []Metric
+	for _, cwName := range cwNames {
+		if ms, ok := known[cwName]; ok {
+			res = append(res, ms...)
+			continue
+		}
+		name := config.DefaultPrometheusName(cwName)
+		if _, ok := names[name]; ok {
+			level.Debug(logger).Log("msg", fmt.Sprintf("Skipping discovered metric %s: %s is already used.", cwName, name))
+			continue
+		}
+		res = append(res, Metric{
+			cwName:         cwName,
+			prometheusName: name,
+			prometheusHelp: cwName,
+		})
+	}
+	return res
+}
+
+// listMetrics returns names of recently active AWS/RDS metrics with only DBInstanceIdentifier dimension
+// keyed by instance identifier.
+func listMetrics(ctx context.Context, session *session.Session) (map[string][]string, error) {
+	input := &cloudwatch.ListMetricsInput{
+		Namespace:      aws.String("AWS/RDS"),
+		Dimensions:     []*cloudwatch.DimensionFilter{{Name: aws.String("DBInstanceIdentifier")}},
+		RecentlyActive: aws.String(recentlyActive),
+	}
+	res := make(map[string][]string)
+	err := cloudwatch.New(session).ListMetricsPagesWithContext(client.WithCollector(ctx, "basic"), input, func(output *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		for _, m := range output.Metrics {
+			if len(m.Dimensions) != 1 || aws.StringValue(m.Dimensions[0].Name) != "DBInstanceIdentifier" {
+				continue
+			}
+			instance := aws.StringValue(m.Dimensions[0].Value)
+			res[instance] = append(res[instance], aws.StringValue(m.MetricName))
+		}
+		return true // continue pagination
+	})
+	return res, err
+}
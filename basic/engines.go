@@ -0,0 +1,22 @@
+package basic
+
+import (
+	"path"
+)
+
+// auroraEngines are engine patterns of built-in cluster metrics.
+var auroraEngines = []string{"aurora*"}
+
+// appliesTo returns true if metric is available for instances with given engine.
+// Metrics without engine patterns, and instances with unknown engine match everything.
+func (m Metric) appliesTo(engine string) bool {
+	if len(m.engines) == 0 || engine == "" {
+		return true
+	}
+	for _, pattern := range m.engines {
+		if ok, _ := path.Match(pattern, engine); ok {
+			return true
+		}
+	}
+	return false
+}
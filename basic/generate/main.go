@@ -0,0 +1,99 @@
+// Command generate generates basic metrics list (basic/metrics.go) from metric descriptions file
+// and, optionally, cloudwatch:ListMetrics output. It is run by go generate in basic directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+)
+
+var metricsTemplate = template.Must(template.New("metrics").Funcs(template.FuncMap{
+	"engines": func(engines []string) string {
+		quoted := make([]string, len(engines))
+		for i, e := range engines {
+			quoted[i] = fmt.Sprintf("%q", e)
+		}
+		return "[]string{" + strings.Join(quoted, ", ") + "}"
+	},
+}).Parse(`// Code generated by generate/main.go; DO NOT EDIT.
+
+package basic
+
+var Metrics = []Metric{
+{{- range . }}
+	{
+		cwName:         {{ printf "%q" .Name }},
+		prometheusName: {{ printf "%q" .PrometheusName }},
+		prometheusHelp: {{ printf "%q" .Help }},
+		{{- if .Engines }}
+		engines:        {{ engines .Engines }},
+		{{- end }}
+	},
+{{- end }}
+}
+`))
+
+func main() {
+	log.SetFlags(0)
+	descriptionsF := flag.String("descriptions", "generate/descriptions.yml", "Metric descriptions file.")
+	listMetricsF := flag.String("list-metrics", "", "cloudwatch:ListMetrics output JSON file; metrics missing in descriptions file are added.")
+	outF := flag.String("out", "metrics.go", "Output file.")
+	flag.Parse()
+
+	descriptions, err := loadDescriptions(*descriptionsF)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *listMetricsF != "" {
+		names, err := loadListMetrics(*listMetricsF)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var added []string
+		descriptions, added = merge(descriptions, names)
+		for _, name := range added {
+			log.Printf("%s is not described in %s, metric name is used as help.", name, *descriptionsF)
+		}
+	} else {
+		descriptions, _ = merge(descriptions, nil)
+	}
+
+	if err = check(descriptions); err != nil {
+		log.Fatal(err)
+	}
+
+	type metric struct {
+		Name           string
+		PrometheusName string
+		Help           string
+		Engines        []string
+	}
+	metrics := make([]metric, len(descriptions))
+	for i, d := range descriptions {
+		metrics[i] = metric{
+			Name:           d.Name,
+			PrometheusName: d.prometheusName(),
+			Help:           d.help(),
+			Engines:        d.Engines,
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = metricsTemplate.Execute(&buf, metrics); err != nil {
+		log.Fatal(err)
+	}
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(*outF, b, 0o644); err != nil { //nolint:gosec
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// description represents a single CloudWatch metric from description file.
+type description struct {
+	Name           string   `yaml:"name"`
+	Description    string   `yaml:"description"` // metric name is used if empty
+	Unit           string   `yaml:"unit"`
+	Engines        []string `yaml:"engines"`         // all engines if empty
+	PrometheusName string   `yaml:"prometheus_name"` // config.DefaultPrometheusName if empty
+}
+
+// listMetricsOutput represents cloudwatch:ListMetrics output captured to JSON:
+// aws cloudwatch list-metrics --namespace AWS/RDS --dimensions Name=DBInstanceIdentifier > list-metrics.json
+type listMetricsOutput struct {
+	Metrics []struct {
+		Namespace  string `json:"Namespace"`
+		MetricName string `json:"MetricName"`
+		Dimensions []struct {
+			Name  string `json:"Name"`
+			Value string `json:"Value"`
+		} `json:"Dimensions"`
+	} `json:"Metrics"`
+}
+
+// prometheusName returns Prometheus metric name.
+func (d description) prometheusName() string {
+	if d.PrometheusName != "" {
+		return d.PrometheusName
+	}
+	return config.DefaultPrometheusName(d.Name)
+}
+
+// help returns Prometheus metric help with units.
+func (d description) help() string {
+	res := d.Description
+	if res == "" {
+		res = d.Name
+	}
+	if d.Unit != "" {
+		res += " Units: " + d.Unit
+	}
+	return res
+}
+
+// loadDescriptions loads metric descriptions from YAML file.
+func loadDescriptions(filename string) ([]description, error) {
+	b, err := os.ReadFile(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Metrics []description `yaml:"metrics"`
+	}
+	if err = yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return file.Metrics, nil
+}
+
+// loadListMetrics loads names of AWS/RDS metrics with only DBInstanceIdentifier dimension
+// from cloudwatch:ListMetrics output.
+func loadListMetrics(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var output listMetricsOutput
+	if err = json.Unmarshal(b, &output); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	seen := make(map[string]struct{})
+	var res []string
+	for _, m := range output.Metrics {
+		if m.Namespace != "AWS/RDS" || len(m.Dimensions) != 1 || m.Dimensions[0].Name != "DBInstanceIdentifier" {
+			continue
+		}
+		if _, ok := seen[m.MetricName]; ok {
+			continue
+		}
+		seen[m.MetricName] = struct{}{}
+		res = append(res, m.MetricName)
+	}
+	return res, nil
+}
+
+// merge adds metrics with given names that are missing in descriptions, and returns sorted descriptions
+// and names of added metrics.
+func merge(descriptions []description, names []string) ([]description, []string) {
+	known := make(map[string]struct{}, len(descriptions))
+	for _, d := range descriptions {
+		known[d.Name] = struct{}{}
+	}
+
+	res := append([]description(nil), descriptions...)
+	var added []string
+	for _, name := range names {
+		if _, ok := known[name]; ok {
+			continue
+		}
+		known[name] = struct{}{}
+		res = append(res, description{Name: name})
+		added = append(added, name)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, added
+}
+
+// check returns an error for duplicate CloudWatch metrics, and for invalid or colliding Prometheus names.
+func check(descriptions []description) error {
+	cwNames := make(map[string]struct{}, len(descriptions))
+	names := make(map[string]string, len(descriptions)) // Prometheus name => CloudWatch name
+	for _, d := range descriptions {
+		if d.Name == "" {
+			return fmt.Errorf("metric name is required")
+		}
+		if _, ok := cwNames[d.Name]; ok {
+			return fmt.Errorf("%s: duplicate metric", d.Name)
+		}
+		cwNames[d.Name] = struct{}{}
+
+		name := d.prometheusName()
+		if !model.IsValidMetricName(model.LabelValue(name)) {
+			return fmt.Errorf("%s: invalid Prometheus name %q", d.Name, name)
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("%s: Prometheus name %q is already used by %s", d.Name, name, other)
+		}
+		names[name] = d.Name
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptions(t *testing.T) {
+	descriptions, err := loadDescriptions("descriptions.yml")
+	require.NoError(t, err)
+	descriptions, _ = merge(descriptions, nil)
+	require.NoError(t, check(descriptions))
+
+	names, err := loadListMetrics(filepath.Join("testdata", "list-metrics.json"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CPUUtilization", "CheckpointLag"}, names)
+
+	merged, added := merge(descriptions, names)
+	assert.Equal(t, []string{"CheckpointLag"}, added)
+	assert.Len(t, merged, len(descriptions)+1)
+	for _, d := range merged {
+		if d.Name == "CheckpointLag" {
+			assert.Equal(t, "aws_rds_checkpoint_lag_average", d.prometheusName())
+			assert.Equal(t, "CheckpointLag", d.help())
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, check([]description{{Name: "ReadIOPS"}, {Name: "WriteIOPS"}}))
+
+	for name, descriptions := range map[string][]description{
+		"duplicate": {{Name: "ReadIOPS"}, {Name: "ReadIOPS", PrometheusName: "read_iops"}},
+		"collision": {{Name: "ReadIOPS"}, {Name: "ReadIOPs"}},
+		"override":  {{Name: "ReadIOPS"}, {Name: "WriteIOPS", PrometheusName: "aws_rds_read_iops_average"}},
+		"invalid":   {{Name: "EBSIOBalance%"}},
+		"empty":     {{Description: "no name"}},
+	} {
+		assert.Error(t, check(descriptions), name)
+	}
+}
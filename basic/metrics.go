@@ -1,50 +1,67 @@
+// Code generated by generate/main.go; DO NOT EDIT.
+
 package basic
 
 var Metrics = []Metric{
+	{
+		cwName:         "ACUUtilization",
+		prometheusName: "aws_rds_acu_utilization_average",
+		prometheusHelp: "The value of the ServerlessDatabaseCapacity metric divided by the maximum ACU value of the DB cluster. Applies to Aurora Serverless v2 instances. Units: Percent",
+		engines:        []string{"aurora*"},
+	},
 	{
 		cwName:         "ActiveTransactions",
 		prometheusName: "aws_rds_active_transactions_average",
 		prometheusHelp: "ActiveTransactions",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "AuroraBinlogReplicaLag",
 		prometheusName: "aws_rds_aurora_binlog_replica_lag_average",
 		prometheusHelp: "AuroraBinlogReplicaLag",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "AuroraReplicaLag",
 		prometheusName: "aws_rds_aurora_replica_lag_average",
 		prometheusHelp: "AuroraReplicaLag",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "AuroraReplicaLagMaximum",
 		prometheusName: "aws_rds_aurora_replica_lag_maximum_average",
 		prometheusHelp: "AuroraReplicaLagMaximum",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "AuroraReplicaLagMinimum",
 		prometheusName: "aws_rds_aurora_replica_lag_minimum_average",
 		prometheusHelp: "AuroraReplicaLagMinimum",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "BinLogDiskUsage",
 		prometheusName: "aws_rds_bin_log_disk_usage_average",
 		prometheusHelp: "The amount of disk space occupied by binary logs on the master. Applies to MySQL read replicas. Units: Bytes",
+		engines:        []string{"mariadb", "mysql"},
 	},
 	{
 		cwName:         "BlockedTransactions",
 		prometheusName: "aws_rds_blocked_transactions_average",
 		prometheusHelp: "BlockedTransactions",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "BufferCacheHitRatio",
 		prometheusName: "aws_rds_buffer_cache_hit_ratio_average",
 		prometheusHelp: "BufferCacheHitRatio",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "BurstBalance",
 		prometheusName: "aws_rds_burst_balance_average",
 		prometheusHelp: "The percent of General Purpose SSD (gp2) burst-bucket I/O credits available. Units: Percent",
+		engines:        []string{"mariadb", "mysql", "postgres", "oracle*", "sqlserver*", "db2*", "custom-*"},
 	},
 	{
 		cwName:         "CPUCreditBalance",
@@ -65,31 +82,52 @@ var Metrics = []Metric{
 		cwName:         "CommitLatency",
 		prometheusName: "aws_rds_commit_latency_average",
 		prometheusHelp: "CommitLatency",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "CommitThroughput",
 		prometheusName: "aws_rds_commit_throughput_average",
 		prometheusHelp: "CommitThroughput",
+		engines:        []string{"aurora*"},
+	},
+	{
+		cwName:         "DBLoad",
+		prometheusName: "aws_rds_db_load_average",
+		prometheusHelp: "The number of active sessions for the DB engine. Performance Insights must be enabled. Units: Count",
+	},
+	{
+		cwName:         "DBLoadCPU",
+		prometheusName: "aws_rds_db_load_cpu_average",
+		prometheusHelp: "The number of active sessions where the wait event type is CPU. Performance Insights must be enabled. Units: Count",
+	},
+	{
+		cwName:         "DBLoadNonCPU",
+		prometheusName: "aws_rds_db_load_non_cpu_average",
+		prometheusHelp: "The number of active sessions where the wait event type is not CPU. Performance Insights must be enabled. Units: Count",
 	},
 	{
 		cwName:         "DDLLatency",
 		prometheusName: "aws_rds_ddl_latency_average",
 		prometheusHelp: "DDLLatency",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "DDLThroughput",
 		prometheusName: "aws_rds_ddl_throughput_average",
 		prometheusHelp: "DDLThroughput",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "DMLLatency",
 		prometheusName: "aws_rds_dml_latency_average",
 		prometheusHelp: "DMLLatency",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "DMLThroughput",
 		prometheusName: "aws_rds_dml_throughput_average",
 		prometheusHelp: "DMLThroughput",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "DatabaseConnections",
@@ -100,16 +138,19 @@ var Metrics = []Metric{
 		cwName:         "Deadlocks",
 		prometheusName: "aws_rds_deadlocks_average",
 		prometheusHelp: "Deadlocks",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "DeleteLatency",
 		prometheusName: "aws_rds_delete_latency_average",
 		prometheusHelp: "DeleteLatency",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "DeleteThroughput",
 		prometheusName: "aws_rds_delete_throughput_average",
 		prometheusHelp: "DeleteThroughput",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "DiskQueueDepth",
@@ -120,16 +161,19 @@ var Metrics = []Metric{
 		cwName:         "EngineUptime",
 		prometheusName: "node_boot_time_seconds",
 		prometheusHelp: "EngineUptime",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "FreeLocalStorage",
 		prometheusName: "aws_rds_free_local_storage_average",
 		prometheusHelp: "FreeLocalStorage",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "FreeStorageSpace",
 		prometheusName: "node_filesystem_free_bytes",
 		prometheusHelp: "The amount of available storage space. Units: Bytes",
+		engines:        []string{"mariadb", "mysql", "postgres", "oracle*", "sqlserver*", "db2*", "custom-*"},
 	},
 	{
 		cwName:         "FreeableMemory",
@@ -140,16 +184,25 @@ var Metrics = []Metric{
 		cwName:         "InsertLatency",
 		prometheusName: "aws_rds_insert_latency_average",
 		prometheusHelp: "InsertLatency",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "InsertThroughput",
 		prometheusName: "aws_rds_insert_throughput_average",
 		prometheusHelp: "InsertThroughput",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "LoginFailures",
 		prometheusName: "aws_rds_login_failures_average",
 		prometheusHelp: "LoginFailures",
+		engines:        []string{"aurora", "aurora-mysql"},
+	},
+	{
+		cwName:         "MaximumUsedTransactionIDs",
+		prometheusName: "aws_rds_maximum_used_transaction_ids_average",
+		prometheusHelp: "The maximum transaction IDs that have been used. Units: Count",
+		engines:        []string{"postgres", "aurora-postgresql"},
 	},
 	{
 		cwName:         "NetworkReceiveThroughput",
@@ -160,16 +213,24 @@ var Metrics = []Metric{
 		cwName:         "NetworkThroughput",
 		prometheusName: "aws_rds_network_throughput_average",
 		prometheusHelp: "NetworkThroughput",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "NetworkTransmitThroughput",
 		prometheusName: "aws_rds_network_transmit_throughput_average",
 		prometheusHelp: "The outgoing (Transmit) network traffic on the DB instance, including both customer database traffic and Amazon RDS traffic used for monitoring and replication. Units: Bytes/second",
 	},
+	{
+		cwName:         "OldestReplicationSlotLag",
+		prometheusName: "aws_rds_oldest_replication_slot_lag_average",
+		prometheusHelp: "The lagging size of the replica lagging the most in terms of write-ahead log (WAL) data received. Units: Bytes",
+		engines:        []string{"postgres"},
+	},
 	{
 		cwName:         "Queries",
 		prometheusName: "aws_rds_queries_average",
 		prometheusHelp: "Queries",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "ReadIOPS",
@@ -186,50 +247,70 @@ var Metrics = []Metric{
 		prometheusName: "aws_rds_read_throughput_average",
 		prometheusHelp: "The average number of bytes read from disk per second. Units: Bytes/Second",
 	},
+	{
+		cwName:         "ReplicaLag",
+		prometheusName: "aws_rds_replica_lag",
+		prometheusHelp: "The amount of time a read replica DB instance lags behind the source DB instance. Units: Seconds",
+		engines:        []string{"mariadb", "mysql", "postgres", "oracle*", "sqlserver*", "db2*", "custom-*"},
+	},
+	{
+		cwName:         "ReplicationSlotDiskUsage",
+		prometheusName: "aws_rds_replication_slot_disk_usage_average",
+		prometheusHelp: "The disk space used by replication slot files. Units: Bytes",
+		engines:        []string{"postgres", "aurora-postgresql"},
+	},
 	{
 		cwName:         "ResultSetCacheHitRatio",
 		prometheusName: "aws_rds_result_set_cache_hit_ratio_average",
 		prometheusHelp: "ResultSetCacheHitRatio",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "SelectLatency",
 		prometheusName: "aws_rds_select_latency_average",
 		prometheusHelp: "SelectLatency",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "SelectThroughput",
 		prometheusName: "aws_rds_select_throughput_average",
 		prometheusHelp: "SelectThroughput",
+		engines:        []string{"aurora", "aurora-mysql"},
+	},
+	{
+		cwName:         "ServerlessDatabaseCapacity",
+		prometheusName: "aws_rds_serverless_database_capacity_average",
+		prometheusHelp: "The current capacity of an Aurora Serverless v2 instance, in Aurora capacity units (ACUs). Units: Count",
+		engines:        []string{"aurora*"},
 	},
 	{
 		cwName:         "SwapUsage",
 		prometheusName: "aws_rds_swap_usage_average",
 		prometheusHelp: "The amount of swap space used on the DB instance. Units: Bytes",
 	},
+	{
+		cwName:         "TransactionLogsDiskUsage",
+		prometheusName: "aws_rds_transaction_logs_disk_usage_average",
+		prometheusHelp: "The disk space used by transaction logs. Units: Bytes",
+		engines:        []string{"postgres", "aurora-postgresql"},
+	},
+	{
+		cwName:         "TransactionLogsGeneration",
+		prometheusName: "aws_rds_transaction_logs_generation_average",
+		prometheusHelp: "The size of transaction logs generated per second. Units: Bytes/Second",
+		engines:        []string{"postgres"},
+	},
 	{
 		cwName:         "UpdateLatency",
 		prometheusName: "aws_rds_update_latency_average",
 		prometheusHelp: "UpdateLatency",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "UpdateThroughput",
 		prometheusName: "aws_rds_update_throughput_average",
 		prometheusHelp: "UpdateThroughput",
-	},
-	{
-		cwName:         "VolumeBytesUsed",
-		prometheusName: "aws_rds_volume_bytes_used_average",
-		prometheusHelp: "VolumeBytesUsed",
-	},
-	{
-		cwName:         "VolumeReadIOPs",
-		prometheusName: "aws_rds_volume_read_io_ps_average",
-		prometheusHelp: "VolumeReadIOPs",
-	},
-	{
-		cwName:         "VolumeWriteIOPs",
-		prometheusName: "aws_rds_volume_write_io_ps_average",
-		prometheusHelp: "VolumeWriteIOPs",
+		engines:        []string{"aurora", "aurora-mysql"},
 	},
 	{
 		cwName:         "WriteIOPS",
@@ -246,9 +327,4 @@ var Metrics = []Metric{
 		prometheusName: "aws_rds_write_throughput_average",
 		prometheusHelp: "The average number of bytes written to disk per second. Units: Bytes/Second",
 	},
-	{
-		cwName:         "ReplicaLag",
-		prometheusName: "aws_rds_replica_lag",
-		prometheusHelp: "The amount of time a read replica DB instance lags behind the source DB instance. Unit: Seconds",
-	},
 }
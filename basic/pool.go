@@ -0,0 +1,89 @@
+package basic
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// QueueDepth tracks GetMetricData requests waiting for a worker.
+	QueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "rds_exporter_basic_queue_depth",
+		Help: "Number of basic and proxy metrics GetMetricData requests waiting for a worker.",
+	})
+
+	// InFlight tracks GetMetricData requests being made by workers.
+	InFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "rds_exporter_basic_requests_in_flight",
+		Help: "Number of basic and proxy metrics GetMetricData requests being made, including pagination and retries.",
+	})
+)
+
+// Pool runs GetMetricData requests of all scrapers with bounded concurrency.
+type Pool struct {
+	ctx   context.Context
+	queue chan func()
+
+	m       sync.Mutex
+	workers []context.CancelFunc // one per running worker
+}
+
+// NewPool creates a new pool and starts given number of workers that run until context is canceled.
+func NewPool(ctx context.Context, workers int) *Pool {
+	p := &Pool{
+		ctx:   ctx,
+		queue: make(chan func()),
+	}
+	p.Resize(workers)
+	return p
+}
+
+// Resize starts or stops workers, so given number of them is running.
+// Stopped workers finish requests they are making.
+func (p *Pool) Resize(workers int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for len(p.workers) < workers {
+		ctx, cancel := context.WithCancel(p.ctx)
+		p.workers = append(p.workers, cancel)
+		go p.worker(ctx)
+	}
+	for len(p.workers) > workers {
+		last := len(p.workers) - 1
+		p.workers[last]()
+		p.workers = p.workers[:last]
+	}
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	// do not wait for the next request if worker was stopped while making the previous one
+	for ctx.Err() == nil {
+		select {
+		case f := <-p.queue:
+			QueueDepth.Dec()
+			InFlight.Inc()
+			f()
+			InFlight.Dec()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run waits for a free worker, runs f in it, and waits for f to return.
+// f is not run if context is canceled before a worker is available.
+func (p *Pool) run(ctx context.Context, f func()) error {
+	done := make(chan struct{})
+	QueueDepth.Inc()
+	select {
+	case p.queue <- func() { defer close(done); f() }:
+		<-done
+		return nil
+	case <-ctx.Done():
+		QueueDepth.Dec()
+		return ctx.Err()
+	}
+}
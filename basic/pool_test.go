@@ -0,0 +1,87 @@
+package basic
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertWorkers runs several blocking functions in pool and checks that given number of them run concurrently,
+// while others wait for a worker. Then it releases them and waits for all of them to finish.
+func assertWorkers(t *testing.T, p *Pool, workers int) {
+	t.Helper()
+
+	const total = 10
+	queued := testutil.ToFloat64(QueueDepth)
+	release := make(chan struct{})
+	var running int32
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.run(context.Background(), func() {
+				atomic.AddInt32(&running, 1)
+				<-release
+			})
+			assert.NoError(t, err)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&running) == int32(workers) && testutil.ToFloat64(QueueDepth)-queued == total-float64(workers)
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(total), atomic.LoadInt32(&running))
+	require.Eventually(t, func() bool { return testutil.ToFloat64(InFlight) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestPool(t *testing.T) {
+	poolCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	p := NewPool(poolCtx, 2)
+	assertWorkers(t, p, 2)
+
+	// occupy both workers, and check that queued request is canceled
+	release := make(chan struct{})
+	started := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			_ = p.run(context.Background(), func() { started <- struct{}{}; <-release })
+		}()
+	}
+	<-started
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var called bool
+	err := p.run(ctx, func() { called = true })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, called)
+	close(release)
+}
+
+func TestPoolResize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPool(ctx, 2)
+
+	p.Resize(3)
+	assertWorkers(t, p, 3)
+	p.Resize(1)
+	assertWorkers(t, p, 1)
+
+	// no workers are left after pool context is canceled
+	cancel()
+	runCtx, runCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer runCancel()
+	err := p.run(runCtx, func() {})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
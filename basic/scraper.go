@@ -1,15 +1,23 @@
 package basic
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/duyhai-bic/rds_exporter/client"
+	"github.com/duyhai-bic/rds_exporter/config"
 	"github.com/duyhai-bic/rds_exporter/sessions"
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
 var (
@@ -18,129 +26,396 @@ var (
 	Range  = 600 * time.Second
 )
 
-type Scraper struct {
-	// params
-	instance  *sessions.Instance
-	collector *Collector
-	ch        chan<- prometheus.Metric
+// maxQueries is the maximal number of metric queries in a single GetMetricData request.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricData.html
+const maxQueries = 500
 
-	// internal
-	svc         *cloudwatch.CloudWatch
-	constLabels prometheus.Labels
+// target is an instance or a cluster that metrics are queried for.
+type target struct {
+	key      string            // cache key
+	labels   prometheus.Labels // constant labels of all metrics
+	instance string            // DBInstanceIdentifier dimension value
+	cluster  string            // DBClusterIdentifier dimension value
+	role     string            // Role dimension value
+	engine   string            // may be empty if unknown
 }
 
-func NewScraper(instance *sessions.Instance, collector *Collector, ch chan<- prometheus.Metric) *Scraper {
-	// Create CloudWatch client
-	sess, _ := collector.sessions.GetSession(instance.Region, instance.Instance)
-	if sess == nil {
-		return nil
+// query is a single statistic of a single metric of a single target.
+type query struct {
+	target    *target
+	metric    Metric
+	statistic string
+	name      string // Prometheus metric name
+}
+
+// namedMetric is a metric that knows its name, help, and type, so it can be gathered without a registry.
+type namedMetric struct {
+	prometheus.Metric
+	name    string
+	help    string
+	counter bool // gauge if false
+}
+
+// scraper retrieves basic metrics of several RDS instances sharing a single session.
+type scraper struct {
+	instances      []sessions.Instance
+	metrics        []Metric
+	discovered     map[string][]Metric // instance cache key => discovered metrics; nil if discovery is disabled
+	statisticLabel bool
+	timestamps     bool
+	allDatapoints  bool
+	svc            *cloudwatch.CloudWatch
+	pool           *Pool
+	logger         log.Logger
+
+	transforms *transform.Rules   // default rules unless set by collector
+	capacities map[string]float64 // instance cache key => the latest ServerlessDatabaseCapacity; set by scrape
+}
+
+func newScraper(session *session.Session, instances []sessions.Instance, metrics []Metric, discovered map[string][]Metric,
+	opts config.Basic, pool *Pool, logger log.Logger,
+) *scraper {
+	return &scraper{
+		instances:      instances,
+		metrics:        metrics,
+		discovered:     discovered,
+		statisticLabel: opts.StatisticLabel,
+		timestamps:     opts.Timestamps || opts.AllDatapoints,
+		allDatapoints:  opts.AllDatapoints,
+		svc:            cloudwatch.New(session),
+		pool:           pool,
+		logger:         logger,
+		transforms:     transform.New(nil),
 	}
-	svc := cloudwatch.New(sess)
+}
 
-	constLabels := prometheus.Labels{
+// constLabels returns labels for all metrics of given instance.
+func constLabels(instance *sessions.Instance) prometheus.Labels {
+	return withMetricLabels(prometheus.Labels{
 		"region":   instance.Region,
 		"instance": instance.Instance,
-	}
-	for n, v := range instance.Labels {
+	}, instance)
+}
+
+// withMetricLabels adds extra labels of given instance to res and returns it.
+func withMetricLabels(res prometheus.Labels, instance *sessions.Instance) prometheus.Labels {
+	for n, v := range instance.MetricLabels() {
 		if v == "" {
-			delete(constLabels, n)
+			delete(res, n)
 		} else {
-			constLabels[n] = v
+			res[n] = v
 		}
 	}
+	return res
+}
 
-	return &Scraper{
-		// params
-		instance:  instance,
-		collector: collector,
-		ch:        ch,
+// queries returns all metric queries for all instances and their clusters keyed by query ID.
+func (s *scraper) queries() (map[string]query, []*cloudwatch.MetricDataQuery) {
+	refs := make(map[string]query, len(s.instances)*len(s.metrics))
+	queries := make([]*cloudwatch.MetricDataQuery, 0, len(s.instances)*len(s.metrics))
+	add := func(t *target, metric Metric) {
+		statistics, names := metric.series(s.statisticLabel)
+		for j, statistic := range statistics {
+			id := fmt.Sprintf("m%d", len(queries))
+			refs[id] = query{
+				target:    t,
+				metric:    metric,
+				statistic: statistic,
+				name:      names[j],
+			}
+			queries = append(queries, metric.query(id, t, statistic))
+		}
+	}
+
+	for i := range s.instances {
+		instance := &s.instances[i]
+		t := &target{
+			key:      cacheKey(instance),
+			labels:   constLabels(instance),
+			instance: instance.Instance,
+			engine:   instance.Engine,
+		}
+		// discovered metrics replace configured ones; all configured metrics are used if instance is not discovered yet
+		// or has no recently active metrics
+		discovered, ok := s.discovered[t.key]
+		for _, metric := range s.metrics {
+			if metric.clusterScoped() || !metric.appliesTo(instance.Engine) || (ok && metric.discoverable()) {
+				continue
+			}
+			if metric.serverless() && !instance.Serverless() {
+				continue
+			}
+			add(t, metric)
+		}
+		for _, metric := range discovered {
+			add(t, metric)
+		}
+	}
 
-		// internal
-		svc:         svc,
-		constLabels: constLabels,
+	for _, instance := range clusters(s.instances) {
+		targets := make(map[string]*target) // role => target
+		for _, metric := range s.metrics {
+			if !metric.clusterScoped() || !metric.appliesTo(instance.Engine) {
+				continue
+			}
+			if metric.serverless() && instance.MaxCapacity == 0 {
+				continue
+			}
+			for _, role := range metric.roles() {
+				if targets[role] == nil {
+					targets[role] = clusterTarget(instance, role)
+				}
+				add(targets[role], metric)
+			}
+		}
 	}
+
+	return refs, queries
 }
 
-func getLatestDatapoint(datapoints []*cloudwatch.Datapoint) *cloudwatch.Datapoint {
-	var latest *cloudwatch.Datapoint = nil
+// query returns GetMetricData query for given target and statistic.
+func (m Metric) query(id string, t *target, statistic string) *cloudwatch.MetricDataQuery {
+	namespace := m.namespace
+	if namespace == "" {
+		namespace = "AWS/RDS"
+	}
+	period := m.period
+	if period == 0 {
+		period = Period
+	}
 
-	for dp := range datapoints {
-		if latest == nil || latest.Timestamp.Before(*datapoints[dp].Timestamp) {
-			latest = datapoints[dp]
+	dimensions := make([]*cloudwatch.Dimension, 0, len(m.dimensions))
+	for _, d := range m.dimensions {
+		value := d.Value
+		if value == "" {
+			switch d.Name {
+			case "DBInstanceIdentifier":
+				value = t.instance
+			case "DBClusterIdentifier":
+				value = t.cluster
+			case "Role":
+				value = t.role
+			}
 		}
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(d.Name),
+			Value: aws.String(value),
+		})
+	}
+	if len(dimensions) == 0 {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String("DBInstanceIdentifier"),
+			Value: aws.String(t.instance),
+		})
 	}
 
-	return latest
+	q := &cloudwatch.MetricDataQuery{
+		Id: aws.String(id),
+		MetricStat: &cloudwatch.MetricStat{
+			Metric: &cloudwatch.Metric{
+				Namespace:  aws.String(namespace),
+				MetricName: aws.String(m.cwName),
+				Dimensions: dimensions,
+			},
+			Period: aws.Int64(int64(period.Seconds())),
+			Stat:   aws.String(statistic),
+		},
+		ReturnData: aws.Bool(true),
+	}
+	if m.unit != "" {
+		q.MetricStat.Unit = aws.String(m.unit)
+	}
+	return q
 }
 
-// Scrape makes the required calls to AWS CloudWatch by using the parameters in the Collector.
-// Once converted into Prometheus format, the metrics are pushed on the ch channel.
-func (s *Scraper) Scrape() {
-	var wg sync.WaitGroup
-	defer wg.Wait()
+// Datapoint is a single value of a single GetMetricData query.
+type Datapoint struct {
+	Timestamp time.Time
+	Value     float64
+}
 
-	wg.Add(len(s.collector.metrics))
-	for _, metric := range s.collector.metrics {
-		metric := metric
-		go func() {
-			defer wg.Done()
+// bootTimes returns boot times of instances computed from the latest EngineUptime datapoints, keyed by instance cache key.
+// They are used to reset counters made from rates when instance is restarted.
+func bootTimes(refs map[string]query, points map[string][]Datapoint) map[string]time.Time {
+	res := make(map[string]time.Time)
+	for id, dps := range points {
+		ref := refs[id]
+		if ref.metric.cwName != "EngineUptime" || ref.statistic != "Average" || ref.target.instance == "" {
+			continue
+		}
 
-			if err := s.scrapeMetric(metric); err != nil {
-				level.Error(s.collector.l).Log("metric", metric.cwName, "error", err)
+		var latest Datapoint
+		for _, dp := range dps {
+			if dp.Timestamp.After(latest.Timestamp) {
+				latest = dp
 			}
-		}()
+		}
+		res[ref.target.key] = latest.Timestamp.Add(-time.Duration(latest.Value * float64(time.Second)))
 	}
+	return res
 }
 
-func (s *Scraper) scrapeMetric(metric Metric) error {
-	now := time.Now()
-	end := now.Add(-Delay)
-
-	params := &cloudwatch.GetMetricStatisticsInput{
-		EndTime:   aws.Time(end),
-		StartTime: aws.Time(end.Add(-Range)),
+// scrape makes batched GetMetricData requests in the pool and returns metrics keyed by instance or cluster cache key.
+// Instances and clusters without any datapoints are not included.
+func (s *scraper) scrape(ctx context.Context) map[string][]prometheus.Metric {
+	ctx = client.WithCollector(ctx, "basic")
+	refs, queries := s.queries()
 
-		Period:     aws.Int64(int64(Period.Seconds())),
-		MetricName: aws.String(metric.cwName),
-		Namespace:  aws.String("AWS/RDS"),
-		Dimensions: []*cloudwatch.Dimension{},
-		Statistics: aws.StringSlice([]string{"Average"}),
-		Unit:       nil,
+	points := s.pool.GetMetricData(ctx, s.svc, queries, s.allDatapoints, s.logger) // query ID => the latest or all datapoints
+	for id, dps := range points {
+		if len(dps) == 0 {
+			ref := refs[id]
+			EmptyResults.WithLabelValues(ref.target.engine, ref.metric.cwName).Inc()
+			delete(points, id)
+		}
 	}
 
-	params.Dimensions = append(params.Dimensions, &cloudwatch.Dimension{
-		Name:  aws.String("DBInstanceIdentifier"),
-		Value: aws.String(s.instance.Instance),
-	})
+	res := make(map[string][]prometheus.Metric, len(s.instances))
+	s.capacities = make(map[string]float64)
+	bootTimes := bootTimes(refs, points)
+	for id, dps := range points {
+		ref := refs[id]
+
+		// labels identify series for rate_to_counter rules
+		labels := make(map[string]string, len(ref.target.labels)+1)
+		for n, v := range ref.target.labels {
+			labels[n] = v
+		}
+		var variableLabels, labelValues []string
+		if s.statisticLabel {
+			labels["statistic"] = ref.statistic
+			variableLabels, labelValues = []string{"statistic"}, []string{ref.statistic}
+		}
+
+		// datapoints of a single series should be exposed in ascending order
+		sort.Slice(dps, func(i, j int) bool { return dps[i].Timestamp.Before(dps[j].Timestamp) })
 
-	// Call CloudWatch to gather the datapoints
-	resp, err := s.svc.GetMetricStatistics(params)
-	if err != nil {
-		return err
+		key := ref.target.key
+		if ref.metric.cwName == "ServerlessDatabaseCapacity" && ref.statistic == "Average" && ref.target.instance != "" {
+			s.capacities[key] = dps[len(dps)-1].Value
+		}
+		for _, dp := range dps {
+			sample := transform.Sample{
+				Collector:        "basic",
+				CloudWatchMetric: ref.metric.cwName,
+				Statistic:        ref.statistic,
+				Name:             ref.name,
+				Labels:           labels,
+				Value:            dp.Value,
+				BootTime:         bootTimes[key],
+			}
+			if s.timestamps {
+				sample.Timestamp = dp.Timestamp
+			}
+			samples, _ := s.transforms.Apply(sample)
+
+			for _, sample := range samples {
+				valueType := prometheus.GaugeValue
+				if sample.Counter {
+					valueType = prometheus.CounterValue
+				}
+				desc := prometheus.NewDesc(sample.Name, ref.metric.prometheusHelp, variableLabels, ref.target.labels)
+				var metric prometheus.Metric = prometheus.MustNewConstMetric(desc, valueType, sample.Value, labelValues...)
+				if s.timestamps {
+					metric = prometheus.NewMetricWithTimestamp(dp.Timestamp, metric)
+				}
+				res[key] = append(res[key], namedMetric{Metric: metric, name: sample.Name, help: ref.metric.prometheusHelp, counter: sample.Counter})
+			}
+		}
 	}
 
-	// There's nothing in there, don't publish the metric
-	if len(resp.Datapoints) == 0 {
-		return nil
+	// capacity range is known from RDS API even if CloudWatch requests failed
+	for _, instance := range clusters(s.instances) {
+		if metrics := capacityMetrics(clusterTarget(instance, ""), instance); metrics != nil {
+			key := clusterCacheKey(instance)
+			res[key] = append(res[key], metrics...)
+		}
 	}
+	return res
+}
 
-	// Pick the latest datapoint
-	dp := getLatestDatapoint(resp.Datapoints)
+// GetMetricData makes GetMetricData requests for given queries in batches of up to maxQueries concurrently in the pool,
+// and returns the latest datapoints (or all datapoints in the window if allDatapoints is true) keyed by query ID.
+// Queries of failed requests are not included, and queries without datapoints have empty slices; errors are logged.
+func (p *Pool) GetMetricData(ctx context.Context, svc *cloudwatch.CloudWatch, queries []*cloudwatch.MetricDataQuery,
+	allDatapoints bool, logger log.Logger,
+) map[string][]Datapoint {
+	end := time.Now().Add(-Delay)
 
-	// Get the metric.
-	v := aws.Float64Value(dp.Average)
-	switch metric.cwName {
-	case "EngineUptime":
-		// "Fake EngineUptime -> node_boot_time with time.Now().Unix() - EngineUptime."
-		v = float64(time.Now().Unix() - int64(v))
-	}
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	res := make(map[string][]Datapoint, len(queries))
+	for start := 0; start < len(queries); start += maxQueries {
+		stop := start + maxQueries
+		if stop > len(queries) {
+			stop = len(queries)
+		}
 
-	// Send metric.
-	s.ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(metric.prometheusName, metric.prometheusHelp, nil, s.constLabels),
-		prometheus.GaugeValue,
-		v,
-	)
+		input := &cloudwatch.GetMetricDataInput{
+			StartTime:         aws.Time(end.Add(-Range)),
+			EndTime:           aws.Time(end),
+			MetricDataQueries: queries[start:stop],
+			ScanBy:            aws.String(cloudwatch.ScanByTimestampDescending),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			batch, err := p.getMetricData(ctx, svc, input, allDatapoints)
+			if err != nil {
+				if client.IsCircuitOpen(err) {
+					level.Debug(logger).Log("msg", "Circuit breaker is open, skipping scrape.", "error", err)
+					return
+				}
+				level.Error(logger).Log("msg", "Failed to get metric data.", "error", err)
+				return
+			}
 
-	return nil
+			m.Lock()
+			defer m.Unlock()
+			for _, q := range input.MetricDataQueries {
+				id := aws.StringValue(q.Id)
+				res[id] = batch[id]
+			}
+		}()
+	}
+	wg.Wait()
+	return res
+}
+
+// getMetricData makes a single paginated GetMetricData request in the pool,
+// and returns the latest or all datapoints keyed by query ID.
+func (p *Pool) getMetricData(ctx context.Context, svc *cloudwatch.CloudWatch, input *cloudwatch.GetMetricDataInput,
+	allDatapoints bool,
+) (map[string][]Datapoint, error) {
+	points := make(map[string][]Datapoint, len(input.MetricDataQueries))
+	var err error
+	poolErr := p.run(ctx, func() {
+		err = svc.GetMetricDataPagesWithContext(ctx, input, func(output *cloudwatch.GetMetricDataOutput, lastPage bool) bool {
+			for _, result := range output.MetricDataResults {
+				id := aws.StringValue(result.Id)
+				for i, ts := range result.Timestamps {
+					if i >= len(result.Values) {
+						break
+					}
+					dp := Datapoint{
+						Timestamp: aws.TimeValue(ts),
+						Value:     aws.Float64Value(result.Values[i]),
+					}
+					if allDatapoints {
+						points[id] = append(points[id], dp)
+						continue
+					}
+					if cur, ok := points[id]; !ok || cur[0].Timestamp.Before(dp.Timestamp) {
+						points[id] = []Datapoint{dp}
+					}
+				}
+			}
+			return true // continue pagination
+		})
+	})
+	if poolErr != nil {
+		return nil, poolErr
+	}
+	return points, err
 }
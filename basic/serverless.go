@@ -0,0 +1,63 @@
+package basic
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/sessions"
+)
+
+const (
+	minCapacityName = "aws_rds_serverless_v2_min_capacity"
+	minCapacityHelp = "The minimal capacity of Aurora Serverless v2 instances of the cluster, in ACUs."
+
+	maxCapacityName = "aws_rds_serverless_v2_max_capacity"
+	maxCapacityHelp = "The maximal capacity of Aurora Serverless v2 instances of the cluster, in ACUs."
+)
+
+// serverlessMetrics are CloudWatch names of metrics available only for Aurora Serverless v2 instances and clusters.
+var serverlessMetrics = map[string]struct{}{
+	"ACUUtilization":             {},
+	"ServerlessDatabaseCapacity": {},
+}
+
+// serverless returns true if metric is available only for Aurora Serverless v2 instances and clusters.
+func (m Metric) serverless() bool {
+	_, ok := serverlessMetrics[m.cwName]
+	return ok
+}
+
+// capacityMetrics returns configured capacity range of the cluster of given instance,
+// or nil if Serverless v2 scaling is not configured.
+func capacityMetrics(t *target, instance *sessions.Instance) []prometheus.Metric {
+	if instance.MaxCapacity == 0 {
+		return nil
+	}
+
+	minDesc := prometheus.NewDesc(minCapacityName, minCapacityHelp, nil, t.labels)
+	maxDesc := prometheus.NewDesc(maxCapacityName, maxCapacityHelp, nil, t.labels)
+	return []prometheus.Metric{
+		namedMetric{
+			Metric: prometheus.MustNewConstMetric(minDesc, prometheus.GaugeValue, instance.MinCapacity),
+			name:   minCapacityName,
+			help:   minCapacityHelp,
+		},
+		namedMetric{
+			Metric: prometheus.MustNewConstMetric(maxDesc, prometheus.GaugeValue, instance.MaxCapacity),
+			name:   maxCapacityName,
+			help:   maxCapacityHelp,
+		},
+	}
+}
+
+// Capacity returns the latest Average ServerlessDatabaseCapacity of given Aurora Serverless v2 instance,
+// and false if it is unknown.
+func (e *Collector) Capacity(instance *sessions.Instance) (float64, bool) {
+	e.cacheRW.RLock()
+	defer e.cacheRW.RUnlock()
+
+	entry, ok := e.cache[cacheKey(instance)]
+	if !ok || entry.capacity == nil {
+		return 0, false
+	}
+	return *entry.capacity, true
+}
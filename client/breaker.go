@@ -0,0 +1,171 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// Circuit states; they are also values of rds_exporter_circuit_state metric.
+const (
+	circuitClosed   = 0
+	circuitOpen     = 1
+	circuitHalfOpen = 2
+)
+
+// Request results for circuit breaker.
+type result int
+
+const (
+	resultSuccess result = iota
+	resultFailure
+	resultNeutral // request was not completed for reasons unrelated to AWS API availability
+)
+
+// circuitOpenError is returned when request is short-circuited by open circuit breaker.
+type circuitOpenError struct {
+	key string
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %s", e.key)
+}
+
+// Temporary implements interface checked by AWS SDK: short-circuited requests should not be retried.
+func (e *circuitOpenError) Temporary() bool {
+	return false
+}
+
+// IsCircuitOpen returns true if given error (possibly wrapped by AWS SDK) was caused by open circuit breaker.
+func IsCircuitOpen(err error) bool {
+	for err != nil {
+		var e *circuitOpenError
+		if errors.As(err, &e) {
+			return true
+		}
+
+		aerr, ok := err.(awserr.Error)
+		if !ok {
+			return false
+		}
+		err = aerr.OrigErr()
+	}
+	return false
+}
+
+type circuit struct {
+	state    int
+	failures int       // consecutive failures
+	openedAt time.Time // for open state
+	probing  bool      // for half-open state
+}
+
+// breaker is a circuit breaker for AWS API requests keyed by account, region and service.
+// Circuit opens after a number of consecutive failures; after a timeout a single probe request is allowed
+// in half-open state, and circuit either closes or opens again depending on its result.
+type breaker struct {
+	cfg config.CircuitBreaker
+
+	m        sync.Mutex
+	circuits map[string]*circuit // account/region/service => circuit
+	mState   *prometheus.GaugeVec
+}
+
+func newBreaker(cfg config.CircuitBreaker) *breaker {
+	return &breaker{
+		cfg:      cfg,
+		circuits: make(map[string]*circuit),
+		mState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_exporter_circuit_state",
+			Help: "AWS API circuit breaker state: 0 - closed, 1 - open, 2 - half-open.",
+		}, []string{"account", "region", "service"}),
+	}
+}
+
+// setState changes circuit state and updates metric. Caller should hold the lock.
+func (b *breaker) setState(c *circuit, state int, account, region, service string) {
+	c.state = state
+	b.mState.WithLabelValues(account, region, service).Set(float64(state))
+}
+
+// allow returns circuitOpenError if request should not be made.
+func (b *breaker) allow(now time.Time, account, region, service string) error {
+	if b.cfg.FailureThreshold == 0 {
+		return nil
+	}
+
+	key := account + "/" + region + "/" + service
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	c := b.circuits[key]
+	if c == nil {
+		c = new(circuit)
+		b.circuits[key] = c
+		b.setState(c, circuitClosed, account, region, service)
+	}
+
+	switch c.state {
+	case circuitOpen:
+		if now.Sub(c.openedAt) < b.cfg.OpenTimeout {
+			return &circuitOpenError{key: key}
+		}
+		b.setState(c, circuitHalfOpen, account, region, service)
+		c.probing = true
+		return nil
+
+	case circuitHalfOpen:
+		if c.probing {
+			return &circuitOpenError{key: key}
+		}
+		c.probing = true
+		return nil
+
+	default:
+		return nil
+	}
+}
+
+// done records request result.
+func (b *breaker) done(now time.Time, account, region, service string, res result) {
+	if b.cfg.FailureThreshold == 0 {
+		return
+	}
+
+	key := account + "/" + region + "/" + service
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	c := b.circuits[key]
+	if c == nil {
+		return
+	}
+
+	if c.state == circuitHalfOpen {
+		c.probing = false
+	}
+
+	switch res {
+	case resultSuccess:
+		c.failures = 0
+		if c.state != circuitClosed {
+			b.setState(c, circuitClosed, account, region, service)
+		}
+
+	case resultFailure:
+		c.failures++
+		if c.state == circuitHalfOpen || (c.state == circuitClosed && c.failures >= b.cfg.FailureThreshold) {
+			c.openedAt = now
+			b.setState(c, circuitOpen, account, region, service)
+		}
+
+	case resultNeutral:
+		// nothing
+	}
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(config.CircuitBreaker{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+	})
+	now := time.Now()
+	allow := func() error { return b.allow(now, "123456789012", "us-east-1", "logs") }
+	done := func(res result) { b.done(now, "123456789012", "us-east-1", "logs", res) }
+	state := func() int { return b.circuits["123456789012/us-east-1/logs"].state }
+
+	// closed: failures below threshold and neutral results do not open circuit
+	require.NoError(t, allow())
+	done(resultFailure)
+	require.NoError(t, allow())
+	done(resultNeutral)
+	assert.Equal(t, circuitClosed, state())
+
+	// open
+	require.NoError(t, allow())
+	done(resultFailure)
+	assert.Equal(t, circuitOpen, state())
+	err := allow()
+	require.Error(t, err)
+	assert.True(t, IsCircuitOpen(awserr.New("RequestError", "send request failed", &url.Error{Op: "Post", Err: err})))
+	assert.False(t, IsCircuitOpen(errors.New("other")))
+
+	// other keys are not affected
+	require.NoError(t, b.allow(now, "123456789012", "us-west-2", "logs"))
+
+	// half-open: only one probe request is allowed; failure opens circuit again
+	now = now.Add(time.Minute)
+	require.NoError(t, allow())
+	assert.Equal(t, circuitHalfOpen, state())
+	require.Error(t, allow())
+	done(resultFailure)
+	assert.Equal(t, circuitOpen, state())
+	require.Error(t, allow())
+
+	// success closes circuit
+	now = now.Add(time.Minute)
+	require.NoError(t, allow())
+	done(resultSuccess)
+	assert.Equal(t, circuitClosed, state())
+	require.NoError(t, allow())
+}
+
+func TestTransportBreaker(t *testing.T) {
+	status := http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c, err := New(log.NewNopLogger(), Options{
+		CircuitBreaker: config.CircuitBreaker{FailureThreshold: 1, OpenTimeout: time.Minute},
+	})
+	require.NoError(t, err)
+	state := func() int { return c.t.breaker.circuits["//127.0.0.1"].state }
+	do := func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := c.HTTP().Do(req)
+		if err == nil {
+			require.NoError(t, resp.Body.Close())
+		}
+		return err
+	}
+
+	// failures of attempts that will be retried and throttling are not counted
+	require.NoError(t, do(withRetriesLeft(context.Background(), true)))
+	assert.Equal(t, circuitClosed, state())
+	status = http.StatusTooManyRequests
+	require.NoError(t, do(context.Background()))
+	assert.Equal(t, circuitClosed, state())
+
+	// failure of the last attempt opens circuit
+	status = http.StatusInternalServerError
+	require.NoError(t, do(withRetriesLeft(context.Background(), false)))
+	assert.Equal(t, circuitOpen, state())
+	assert.True(t, IsCircuitOpen(do(context.Background())))
+}
@@ -2,28 +2,79 @@ package client
 
 import (
 	"net/http"
-	"time"
 
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/config"
 )
 
 // Client represents HTTP client for all AWS APIs with metrics reporting.
 type Client struct {
 	c *http.Client
 	t *transport
+	r *retryer
+}
+
+// Options represents Client options.
+type Options struct {
+	// LegacySummary enables rds_exporter_responses_durations_seconds summary
+	// instead of rds_exporter_request_duration_seconds histogram.
+	LegacySummary bool
+
+	// LatencyBuckets are rds_exporter_request_duration_seconds histogram buckets; default buckets are used if empty.
+	LatencyBuckets []float64
+
+	// NativeHistogramBucketFactor enables native histogram for rds_exporter_request_duration_seconds if greater than 1.
+	NativeHistogramBucketFactor float64
+
+	// RateLimits are client-side AWS API rate limits.
+	RateLimits []config.RateLimit
+
+	// Retry is AWS API requests retry policy; config.DefaultRetry is used if it is not set.
+	Retry config.Retry
+
+	// CircuitBreaker configures circuit breaker for AWS API requests; it is disabled if not set.
+	CircuitBreaker config.CircuitBreaker
+
+	// HTTPClient configures HTTP client; config.DefaultHTTPClient is used if timeout is not set.
+	HTTPClient config.HTTPClient
+
+	// Prices is AWS API price table for cost accounting; config.DefaultPrices is used if it is not set.
+	Prices []config.Price
 }
 
 // New creates new Client.
-func New(logger log.Logger) *Client {
-	t := newTransport(logger)
+func New(logger log.Logger, opts Options) (*Client, error) {
+	if opts.Retry.MaxAttempts == 0 {
+		opts.Retry = config.DefaultRetry
+	}
+	if opts.Prices == nil {
+		opts.Prices = config.DefaultPrices
+	}
+	if opts.HTTPClient.Timeout == 0 {
+		opts.HTTPClient = config.DefaultHTTPClient
+	}
+
+	ht, err := newHTTPTransport(opts.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTransport(logger, opts, ht)
 	return &Client{
 		c: &http.Client{
+			// timeouts are handled by transport
 			Transport: t,
-			Timeout:   15 * time.Second,
 		},
 		t: t,
-	}
+		r: &retryer{
+			policy:   opts.Retry,
+			slowdown: t.slowdown,
+			mRetries: t.mRetries,
+		},
+	}, nil
 }
 
 // HTTP returns underlying *http.Client.
@@ -31,14 +82,45 @@ func (c *Client) HTTP() *http.Client {
 	return c.c
 }
 
+// Retryer returns AWS SDK retryer with configured retry policy.
+func (c *Client) Retryer() request.Retryer {
+	return c.r
+}
+
+// AccountRateLimits returns true if any rate limit rule selects requests by account,
+// so account ID of sessions should be resolved.
+func (c *Client) AccountRateLimits() bool {
+	for _, rule := range c.t.limiter.rules {
+		if rule.Account != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AttemptHandler returns AWS SDK Send handler that marks request attempts that may be retried,
+// so circuit breaker counts a single failure per request instead of a failure per attempt.
+func (c *Client) AttemptHandler() request.NamedHandler {
+	return request.NamedHandler{
+		Name: "rds_exporter.AttemptHandler",
+		Fn: func(r *request.Request) {
+			r.HTTPRequest = r.HTTPRequest.WithContext(withRetriesLeft(r.HTTPRequest.Context(), r.RetryCount < r.MaxRetries()))
+		},
+	}
+}
+
 // Describe implements prometheus.Collector.
 func (c *Client) Describe(ch chan<- *prometheus.Desc) {
-	c.t.mRequests.Describe(ch)
+	for _, m := range c.t.collectors() {
+		m.Describe(ch)
+	}
 }
 
 // Collect implements prometheus.Collector.
 func (c *Client) Collect(ch chan<- prometheus.Metric) {
-	c.t.mRequests.Collect(ch)
+	for _, m := range c.t.collectors() {
+		m.Collect(ch)
+	}
 }
 
 // check interfaces
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	accountKey contextKey = iota
+	collectorKey
+	retriesLeftKey
+)
+
+// WithAccount returns a copy of ctx with AWS account ID that is used for metrics and limits of requests made with it.
+func WithAccount(ctx context.Context, accountID string) context.Context {
+	return context.WithValue(ctx, accountKey, accountID)
+}
+
+// accountFrom returns AWS account ID from ctx, or empty string.
+func accountFrom(ctx context.Context) string {
+	accountID, _ := ctx.Value(accountKey).(string)
+	return accountID
+}
+
+// withRetriesLeft returns a copy of ctx that marks whether request attempt made with it may be retried by AWS SDK.
+func withRetriesLeft(ctx context.Context, retriesLeft bool) context.Context {
+	return context.WithValue(ctx, retriesLeftKey, retriesLeft)
+}
+
+// retriesLeftFrom returns true if request attempt made with ctx may be retried;
+// attempts of requests not made by AWS SDK are considered the last ones.
+func retriesLeftFrom(ctx context.Context) bool {
+	retriesLeft, _ := ctx.Value(retriesLeftKey).(bool)
+	return retriesLeft
+}
+
+// WithCollector returns a copy of ctx with collector name that is used for metrics of requests made with it.
+func WithCollector(ctx context.Context, collector string) context.Context {
+	return context.WithValue(ctx, collectorKey, collector)
+}
+
+// collectorFrom returns collector name from ctx, or "other".
+func collectorFrom(ctx context.Context) string {
+	if collector, _ := ctx.Value(collectorKey).(string); collector != "" {
+		return collector
+	}
+	return "other"
+}
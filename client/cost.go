@@ -0,0 +1,61 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+const gigabyte = 1 << 30
+
+// pricer computes AWS API requests costs using price table.
+type pricer struct {
+	prices map[string]config.Price // service/operation => price
+}
+
+func newPricer(prices []config.Price) *pricer {
+	p := &pricer{
+		prices: make(map[string]config.Price, len(prices)),
+	}
+	for _, price := range prices {
+		p.prices[price.Service+"/"+price.Operation] = price
+	}
+	return p
+}
+
+// cost returns cost of the request in dollars, and cost of each response byte.
+func (p *pricer) cost(info *requestInfo) (request float64, perByte float64) {
+	price, ok := p.prices[info.service+"/"+info.operation]
+	if !ok {
+		return 0, 0
+	}
+
+	switch price.Unit {
+	case config.PerRequest:
+		return price.Price, 0
+	case config.PerMetric:
+		return price.Price * float64(countMetrics(info.body)), 0
+	case config.PerGigabyte:
+		return 0, price.Price / gigabyte
+	default:
+		return 0, 0
+	}
+}
+
+// countMetrics returns a number of metrics requested by GetMetricData query protocol request body:
+// MetricDataQueries.member.1.MetricStat.Metric.MetricName=CPUUtilization&...
+func countMetrics(body []byte) int {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return 0
+	}
+
+	var res int
+	for k := range values {
+		if strings.HasPrefix(k, "MetricDataQueries.member.") && strings.HasSuffix(k, ".MetricStat.Metric.MetricName") {
+			res++
+		}
+	}
+	return res
+}
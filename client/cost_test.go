@@ -0,0 +1,42 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+func TestPricer(t *testing.T) {
+	p := newPricer(config.DefaultPrices)
+
+	for _, tc := range []struct {
+		info            requestInfo
+		expectedCost    float64
+		expectedPerByte float64
+	}{
+		{
+			info:         requestInfo{service: "monitoring", operation: "GetMetricStatistics"},
+			expectedCost: 0.00001,
+		},
+		{
+			info: requestInfo{service: "monitoring", operation: "GetMetricData", body: []byte("Action=GetMetricData&" +
+				"MetricDataQueries.member.1.Id=m1&MetricDataQueries.member.1.MetricStat.Metric.MetricName=CPUUtilization&" +
+				"MetricDataQueries.member.2.Id=m2&MetricDataQueries.member.2.MetricStat.Metric.MetricName=FreeableMemory&" +
+				"MetricDataQueries.member.3.Id=e1&MetricDataQueries.member.3.Expression=m1*2")},
+			expectedCost: 0.00002,
+		},
+		{
+			info:            requestInfo{service: "logs", operation: "FilterLogEvents"},
+			expectedPerByte: 0.005 / gigabyte,
+		},
+		{
+			info: requestInfo{service: "rds", operation: "DescribeDBInstances"},
+		},
+	} {
+		cost, perByte := p.cost(&tc.info)
+		assert.InDelta(t, tc.expectedCost, cost, 1e-12, tc.info.operation)
+		assert.InDelta(t, tc.expectedPerByte, perByte, 1e-18, tc.info.operation)
+	}
+}
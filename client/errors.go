@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Error classes for rds_exporter_api_errors_total metric.
+const (
+	errorThrottling   = "throttling"
+	errorAccessDenied = "access_denied"
+	errorNotFound     = "not_found"
+	errorOther        = "other"
+)
+
+var accessDeniedCodes = map[string]struct{}{
+	"AccessDenied":                {},
+	"AccessDeniedException":       {},
+	"AuthFailure":                 {},
+	"ExpiredToken":                {},
+	"ExpiredTokenException":       {},
+	"IncompleteSignature":         {},
+	"InvalidClientTokenId":        {},
+	"InvalidSignatureException":   {},
+	"MissingAuthenticationToken":  {},
+	"NotAuthorized":               {},
+	"SignatureDoesNotMatch":       {},
+	"UnauthorizedOperation":       {},
+	"UnrecognizedClientException": {},
+}
+
+// parseErrorCode returns AWS error code from response header or body, or empty string.
+func parseErrorCode(header string, body []byte) string {
+	// JSON protocol may return code in header: X-Amzn-ErrorType: ThrottlingException:http://internal.amazon.com/...
+	if header != "" {
+		return strings.SplitN(header, ":", 2)[0]
+	}
+
+	// JSON protocol: {"__type":"com.amazonaws.logs#ThrottlingException","message":"Rate exceeded"}
+	var j struct {
+		Type string `json:"__type"`
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &j); err == nil {
+		if j.Type != "" {
+			return j.Type[strings.LastIndex(j.Type, "#")+1:]
+		}
+		return j.Code
+	}
+
+	// query protocol: <ErrorResponse><Error><Code>Throttling</Code>...</Error></ErrorResponse>
+	var x struct {
+		Code  string `xml:"Error>Code"`
+		Code2 string `xml:"Errors>Error>Code"`
+	}
+	if err := xml.Unmarshal(body, &x); err == nil {
+		if x.Code != "" {
+			return x.Code
+		}
+		return x.Code2
+	}
+
+	return ""
+}
+
+// classifyError returns error class for given AWS error code and message.
+func classifyError(code string, body []byte) string {
+	switch {
+	case request.IsErrorThrottle(awserr.New(code, "", nil)):
+		return errorThrottling
+	case strings.Contains(string(body), "Rate exceeded"):
+		return errorThrottling
+	}
+
+	if _, ok := accessDeniedCodes[code]; ok {
+		return errorAccessDenied
+	}
+
+	for _, suffix := range []string{"NotFound", "NotFoundFault", "NotFoundException"} {
+		if strings.HasSuffix(code, suffix) {
+			return errorNotFound
+		}
+	}
+
+	return errorOther
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"localhost", ".internal.example.com", "amazonaws.com.cn", "10.0.0.0/8", "192.168.1.1"}
+
+	for host, expected := range map[string]bool{
+		"localhost":                          true,
+		"db.internal.example.com":            true,
+		"internal.example.com":               true,
+		"example.com":                        false,
+		"rds.cn-north-1.amazonaws.com.cn":    true,
+		"monitoring.us-east-1.amazonaws.com": false,
+		"10.1.2.3":                           true,
+		"11.1.2.3":                           false,
+		"192.168.1.1":                        true,
+		"192.168.1.2":                        false,
+	} {
+		assert.Equal(t, expected, bypassProxy(host, noProxy), host)
+	}
+
+	assert.True(t, bypassProxy("monitoring.us-east-1.amazonaws.com", []string{"*"}))
+	assert.False(t, bypassProxy("monitoring.us-east-1.amazonaws.com", nil))
+}
+
+func TestProxyFunc(t *testing.T) {
+	proxy, err := proxyFunc("http://proxy.example.com:3128", []string{"sts.amazonaws.com"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", "https://monitoring.us-east-1.amazonaws.com/", nil)
+	require.NoError(t, err)
+	u, err := proxy(req)
+	require.NoError(t, err)
+	require.NotNil(t, u)
+	assert.Equal(t, "proxy.example.com:3128", u.Host)
+
+	req, err = http.NewRequest("POST", "https://sts.amazonaws.com/", nil)
+	require.NoError(t, err)
+	u, err = proxy(req)
+	require.NoError(t, err)
+	assert.Nil(t, u)
+
+	_, err = proxyFunc("proxy.example.com", nil)
+	assert.Error(t, err)
+}
+
+func TestTimeoutFor(t *testing.T) {
+	opts := Options{
+		HTTPClient: config.HTTPClient{
+			Timeout:         15 * time.Second,
+			ServiceTimeouts: map[string]time.Duration{"logs": time.Minute, "sts": 5 * time.Second},
+		},
+	}
+	tr := newTransport(log.NewNopLogger(), opts, new(http.Transport))
+	assert.Equal(t, time.Minute, tr.timeoutFor("logs"))
+	assert.Equal(t, 5*time.Second, tr.timeoutFor("sts"))
+	assert.Equal(t, 15*time.Second, tr.timeoutFor("monitoring"))
+}
+
+func TestTransportTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	c, err := New(log.NewNopLogger(), Options{
+		HTTPClient: config.HTTPClient{
+			Timeout:         5 * time.Second,
+			ServiceTimeouts: map[string]time.Duration{"127.0.0.1": 100 * time.Millisecond},
+		},
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", srv.URL+"/slow", nil)
+	require.NoError(t, err)
+	_, err = c.HTTP().Do(req) //nolint:bodyclose
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	req, err = http.NewRequestWithContext(context.Background(), "GET", srv.URL+"/fast", nil)
+	require.NoError(t, err)
+	resp, err := c.HTTP().Do(req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "ok", string(b))
+}
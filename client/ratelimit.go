@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// rateLimitError is returned when request is dropped by client-side rate limiter.
+type rateLimitError struct {
+	key  string
+	wait time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("client-side rate limit exceeded for %s: request would wait for %s", e.key, e.wait)
+}
+
+// Temporary implements interface checked by AWS SDK: dropped requests should not be retried.
+func (e *rateLimitError) Temporary() bool {
+	return false
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	m      sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns time to wait before it is available.
+// If wait exceeds maxWait (if it is not zero), token is not taken and false is returned.
+// Negative maxWait means that request can't wait at all.
+func (b *bucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, true
+	}
+
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	if maxWait != 0 && wait > maxWait {
+		b.tokens++
+		return wait, false
+	}
+	return wait, true
+}
+
+// cancel returns a token taken by reserve.
+func (b *bucket) cancel() {
+	b.m.Lock()
+	b.tokens++
+	b.m.Unlock()
+}
+
+// rateLimiter applies the first matching rate limit rule to requests.
+// Each rule limits requests separately for every account, region, service and operation,
+// like AWS API quotas do.
+type rateLimiter struct {
+	rules []config.RateLimit
+
+	m       sync.Mutex
+	buckets map[string]*bucket // rule index + account/region/service/operation => bucket
+}
+
+func newRateLimiter(rules []config.RateLimit) *rateLimiter {
+	return &rateLimiter{
+		rules:   rules,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// matches returns true if rule field is empty or equals to the value (case-insensitively).
+func matches(rule, value string) bool {
+	return rule == "" || strings.EqualFold(rule, value)
+}
+
+// wait blocks until request can be made. It returns wait duration,
+// and rateLimitError if request should be dropped, or context error.
+func (l *rateLimiter) wait(ctx context.Context, account string, info *requestInfo) (time.Duration, error) {
+	for i, rule := range l.rules {
+		if !matches(rule.Account, account) || !matches(rule.Region, info.region) ||
+			!matches(rule.Service, info.service) || !matches(rule.Operation, info.operation) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s/%s/%s", account, info.region, info.service, info.operation)
+		l.m.Lock()
+		b := l.buckets[fmt.Sprintf("%d/%s", i, key)]
+		if b == nil {
+			b = newBucket(rule.Rate, rule.Burst)
+			l.buckets[fmt.Sprintf("%d/%s", i, key)] = b
+		}
+		l.m.Unlock()
+
+		maxWait := rule.MaxWait
+		if deadline, ok := ctx.Deadline(); ok && (maxWait == 0 || time.Until(deadline) < maxWait) {
+			maxWait = time.Until(deadline)
+		}
+		d, ok := b.reserve(time.Now(), maxWait)
+		if !ok {
+			return 0, &rateLimitError{key: key, wait: d}
+		}
+		if d == 0 {
+			return 0, nil
+		}
+
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return d, nil
+		case <-ctx.Done():
+			b.cancel()
+			return d, ctx.Err()
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+func TestBucket(t *testing.T) {
+	b := newBucket(10, 2)
+	now := b.last
+
+	// burst
+	for i := 0; i < 2; i++ {
+		d, ok := b.reserve(now, 0)
+		assert.True(t, ok)
+		assert.Zero(t, d)
+	}
+
+	// next token is available in 100ms
+	d, ok := b.reserve(now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, d)
+
+	// and the next one in 200ms, which is too long
+	d, ok = b.reserve(now, 150*time.Millisecond)
+	assert.False(t, ok)
+	assert.Equal(t, 200*time.Millisecond, d)
+
+	// tokens are refilled, but not above burst
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		d, ok = b.reserve(now, -1)
+		assert.True(t, ok)
+		assert.Zero(t, d)
+	}
+	_, ok = b.reserve(now, -1)
+	assert.False(t, ok)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter([]config.RateLimit{
+		{Service: "monitoring", Rate: 1, Burst: 1, MaxWait: time.Millisecond},
+	})
+	ctx := context.Background()
+	cw := &requestInfo{service: "monitoring", operation: "GetMetricData", region: "us-east-1"}
+	logs := &requestInfo{service: "logs", operation: "FilterLogEvents", region: "us-east-1"}
+
+	_, err := l.wait(ctx, "123456789012", cw)
+	require.NoError(t, err)
+	_, err = l.wait(ctx, "123456789012", cw)
+	require.IsType(t, &rateLimitError{}, err)
+	assert.Contains(t, err.Error(), "client-side rate limit exceeded for 123456789012/us-east-1/monitoring/GetMetricData")
+
+	// separate buckets for other accounts
+	_, err = l.wait(ctx, "210987654321", cw)
+	require.NoError(t, err)
+
+	// no limits for other services
+	for i := 0; i < 10; i++ {
+		_, err = l.wait(ctx, "123456789012", logs)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// requestInfo describes a single AWS API request.
+type requestInfo struct {
+	service   string // endpoint prefix: logs, monitoring, rds, sts, etc.
+	operation string // FilterLogEvents, GetMetricStatistics, DescribeDBInstances, etc.
+	region    string // "global" for global endpoints
+	body      []byte // may be nil
+}
+
+// AWS endpoints DNS suffixes for all partitions and dual-stack endpoints.
+var endpointSuffixes = []string{
+	".amazonaws.com",
+	".amazonaws.com.cn",
+	".api.aws",
+	".api.amazonwebservices.com.cn",
+	".c2s.ic.gov",
+	".sc2s.sgov.gov",
+}
+
+// parseRequest returns information about AWS API request.
+// Request body is read and replaced with an in-memory copy.
+func parseRequest(req *http.Request) (*requestInfo, error) {
+	// logs.us-east-1.amazonaws.com, rds-fips.us-gov-west-1.amazonaws.com, sts.amazonaws.com, etc.
+	host := req.URL.Hostname()
+	info := &requestInfo{
+		service: host,
+	}
+	for _, suffix := range endpointSuffixes {
+		if !strings.HasSuffix(host, suffix) {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(host, suffix), ".")
+		info.service = strings.TrimSuffix(parts[0], "-fips")
+		info.region = "global"
+		if len(parts) > 1 {
+			info.region = parts[1]
+		}
+		break
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		info.body = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		}
+	}
+
+	// JSON protocol (CloudWatch Logs): X-Amz-Target: Logs_20140328.FilterLogEvents
+	if target := req.Header.Get("X-Amz-Target"); target != "" {
+		info.operation = target[strings.LastIndex(target, ".")+1:]
+		return info, nil
+	}
+
+	// query protocol (CloudWatch, RDS, STS): Action=GetMetricStatistics&Version=2010-08-01&...
+	info.operation = req.URL.Query().Get("Action")
+	if info.operation == "" && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(info.body)); err == nil {
+			info.operation = values.Get("Action")
+		}
+	}
+	if info.operation == "" {
+		info.operation = "unknown"
+	}
+
+	return info, nil
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	golden    = flag.Bool("golden", false, "does nothing; exists only for compatibility with other packages")
+	goldenTXT = flag.Bool("golden-txt", false, "does nothing; exists only for compatibility with other packages")
+)
+
+func TestParseRequest(t *testing.T) {
+	for _, tc := range []struct {
+		url, target, body string
+		expected          requestInfo
+	}{
+		{
+			url:      "https://logs.us-east-1.amazonaws.com/",
+			target:   "Logs_20140328.FilterLogEvents",
+			body:     `{"logGroupName":"RDSOSMetrics"}`,
+			expected: requestInfo{service: "logs", operation: "FilterLogEvents", region: "us-east-1"},
+		},
+		{
+			url:      "https://monitoring.us-west-2.amazonaws.com/",
+			body:     "Action=GetMetricStatistics&Version=2010-08-01",
+			expected: requestInfo{service: "monitoring", operation: "GetMetricStatistics", region: "us-west-2"},
+		},
+		{
+			url:      "https://rds-fips.us-gov-west-1.amazonaws.com/",
+			body:     "Action=DescribeDBInstances&Version=2014-10-31",
+			expected: requestInfo{service: "rds", operation: "DescribeDBInstances", region: "us-gov-west-1"},
+		},
+		{
+			url:      "https://rds.cn-north-1.amazonaws.com.cn/",
+			body:     "Action=DescribeDBInstances&Version=2014-10-31",
+			expected: requestInfo{service: "rds", operation: "DescribeDBInstances", region: "cn-north-1"},
+		},
+		{
+			url:      "https://sts.amazonaws.com/",
+			body:     "Action=GetCallerIdentity&Version=2011-06-15",
+			expected: requestInfo{service: "sts", operation: "GetCallerIdentity", region: "global"},
+		},
+		{
+			url:      "http://127.0.0.1:8080/",
+			expected: requestInfo{service: "127.0.0.1", operation: "unknown"},
+		},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			req, err := http.NewRequest("POST", tc.url, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+			if tc.target != "" {
+				req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+				req.Header.Set("X-Amz-Target", tc.target)
+			}
+
+			info, err := parseRequest(req)
+			require.NoError(t, err)
+			if tc.body != "" {
+				tc.expected.body = []byte(tc.body)
+			}
+			assert.Equal(t, &tc.expected, info)
+
+			// body should still be readable
+			b, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.body, string(b))
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		header, body  string
+		expectedCode  string
+		expectedClass string
+	}{
+		{
+			body:          `{"__type":"ThrottlingException","message":"Rate exceeded"}`,
+			expectedCode:  "ThrottlingException",
+			expectedClass: errorThrottling,
+		},
+		{
+			body:          `<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`,
+			expectedCode:  "Throttling",
+			expectedClass: errorThrottling,
+		},
+		{
+			header:        "AccessDeniedException:http://internal.amazon.com/coral/com.amazon.coral.service/",
+			body:          `{"message":"User is not authorized"}`,
+			expectedCode:  "AccessDeniedException",
+			expectedClass: errorAccessDenied,
+		},
+		{
+			body:          `<ErrorResponse><Error><Code>DBInstanceNotFound</Code></Error></ErrorResponse>`,
+			expectedCode:  "DBInstanceNotFound",
+			expectedClass: errorNotFound,
+		},
+		{
+			body:          `{"__type":"com.amazonaws.logs#ResourceNotFoundException"}`,
+			expectedCode:  "ResourceNotFoundException",
+			expectedClass: errorNotFound,
+		},
+		{
+			body:          `<Response><Errors><Error><Code>InternalFailure</Code></Error></Errors></Response>`,
+			expectedCode:  "InternalFailure",
+			expectedClass: errorOther,
+		},
+		{
+			body:          `garbage`,
+			expectedCode:  "",
+			expectedClass: errorOther,
+		},
+	} {
+		code := parseErrorCode(tc.header, []byte(tc.body))
+		assert.Equal(t, tc.expectedCode, code, tc.body)
+		assert.Equal(t, tc.expectedClass, classifyError(code, []byte(tc.body)), tc.body)
+	}
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// backoff returns exponential backoff with jitter for given retry number (starting from 0).
+func backoff(policy config.Retry, retry int) time.Duration {
+	d := policy.BaseBackoff
+	for i := 0; i < retry && d < policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > policy.MaxBackoff {
+		d = policy.MaxBackoff
+	}
+
+	// randomize a part of backoff
+	return d - time.Duration(policy.Jitter*rand.Float64()*float64(d)) //nolint:gosec
+}
+
+// retryer implements AWS SDK request.Retryer with configurable policy.
+type retryer struct {
+	policy   config.Retry
+	slowdown *slowdown
+	mRetries *prometheus.CounterVec
+}
+
+// MaxRetries implements request.Retryer.
+func (r *retryer) MaxRetries() int {
+	return r.policy.MaxAttempts - 1
+}
+
+// ShouldRetry implements request.Retryer.
+func (r *retryer) ShouldRetry(req *request.Request) bool {
+	return awsclient.DefaultRetryer{}.ShouldRetry(req)
+}
+
+// RetryRules implements request.Retryer.
+func (r *retryer) RetryRules(req *request.Request) time.Duration {
+	r.mRetries.WithLabelValues(req.ClientInfo.ServiceName, req.Operation.Name, aws.StringValue(req.Config.Region)).Inc()
+
+	d := backoff(r.policy, req.RetryCount)
+
+	// retry throttled requests not earlier than other requests are allowed
+	if s := r.slowdown.delay(slowdownKey(accountFrom(req.HTTPRequest.Context()), req.ClientInfo.ServiceName,
+		aws.StringValue(req.Config.Region))); s > d {
+		d = s
+	}
+	return d
+}
+
+// slowdownKey returns a key for adaptive slowdown: AWS API quotas are per account, region and service.
+func slowdownKey(account, service, region string) string {
+	return account + "/" + region + "/" + service
+}
+
+// slowdown tracks adaptive delays of requests to account, region and service that were recently throttled.
+// Delay is doubled on every throttling error, and halved on every successful response.
+// Requests are spaced by the delay, so concurrent requests are not made at once after waiting.
+type slowdown struct {
+	policy config.Retry
+
+	m      sync.Mutex
+	delays map[string]time.Duration
+	next   map[string]time.Time // the earliest time of the next request
+}
+
+func newSlowdown(policy config.Retry) *slowdown {
+	return &slowdown{
+		policy: policy,
+		delays: make(map[string]time.Duration),
+		next:   make(map[string]time.Time),
+	}
+}
+
+// delay returns current delay for given key.
+func (s *slowdown) delay(key string) time.Duration {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.delays[key]
+}
+
+// throttled increases delay for given key.
+func (s *slowdown) throttled(key string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	d := s.delays[key] * 2
+	if d < s.policy.BaseBackoff {
+		d = s.policy.BaseBackoff
+	}
+	if d > s.policy.MaxBackoff {
+		d = s.policy.MaxBackoff
+	}
+	s.delays[key] = d
+	if next := time.Now().Add(d); next.After(s.next[key]) {
+		s.next[key] = next
+	}
+}
+
+// succeeded decreases delay for given key.
+func (s *slowdown) succeeded(key string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	d, ok := s.delays[key]
+	if !ok {
+		return
+	}
+	if d /= 2; d < s.policy.BaseBackoff {
+		delete(s.delays, key)
+		delete(s.next, key)
+		return
+	}
+	s.delays[key] = d
+}
+
+// reserve returns how long the next request for given key should wait, and moves the next request time by the delay.
+func (s *slowdown) reserve(now time.Time, key string) time.Duration {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	d := s.delays[key]
+	if d == 0 {
+		return 0
+	}
+	next := s.next[key]
+	if next.Before(now) {
+		next = now
+	}
+	s.next[key] = next.Add(d)
+	return next.Sub(now)
+}
+
+// wait blocks until the time reserved for the request to given key, or until context is canceled.
+func (s *slowdown) wait(ctx context.Context, key string) (time.Duration, error) {
+	d := s.reserve(time.Now(), key)
+	if d == 0 {
+		return 0, nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return d, nil
+	case <-ctx.Done():
+		return d, ctx.Err()
+	}
+}
+
+// check interfaces
+var (
+	_ request.Retryer = (*retryer)(nil)
+)
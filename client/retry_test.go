@@ -0,0 +1,83 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := config.Retry{
+		MaxAttempts: 5,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+	}
+	for retry, expected := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		assert.Equal(t, expected, backoff(policy, retry), "retry %d", retry)
+	}
+
+	policy.Jitter = 0.5
+	for retry := 0; retry < 10; retry++ {
+		d := backoff(policy, 3)
+		assert.True(t, d > 400*time.Millisecond && d <= 800*time.Millisecond, "%s", d)
+	}
+}
+
+func TestSlowdown(t *testing.T) {
+	s := newSlowdown(config.Retry{
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  300 * time.Millisecond,
+	})
+	key := slowdownKey("123456789012", "logs", "us-east-1")
+	assert.Zero(t, s.delay(key))
+
+	s.throttled(key)
+	assert.Equal(t, 100*time.Millisecond, s.delay(key))
+	s.throttled(key)
+	assert.Equal(t, 200*time.Millisecond, s.delay(key))
+	s.throttled(key)
+	assert.Equal(t, 300*time.Millisecond, s.delay(key))
+	assert.Zero(t, s.delay(slowdownKey("123456789012", "monitoring", "us-east-1")))
+
+	s.succeeded(key)
+	assert.Equal(t, 150*time.Millisecond, s.delay(key))
+	s.succeeded(key)
+	assert.Zero(t, s.delay(key))
+}
+
+func TestSlowdownSpacing(t *testing.T) {
+	s := newSlowdown(config.Retry{
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  300 * time.Millisecond,
+	})
+	key := slowdownKey("123456789012", "logs", "us-east-1")
+	now := time.Now()
+	assert.Zero(t, s.reserve(now, key))
+
+	// concurrent requests after throttling are spaced by the delay
+	s.throttled(key)
+	now = time.Now()
+	for i := 1; i <= 3; i++ {
+		assert.InDelta(t, float64(time.Duration(i)*100*time.Millisecond), float64(s.reserve(now, key)), float64(10*time.Millisecond), "%d", i)
+	}
+	assert.Zero(t, s.reserve(now, slowdownKey("123456789012", "monitoring", "us-east-1")))
+
+	// the first request after a pause is not delayed
+	now = now.Add(time.Second)
+	assert.Zero(t, s.reserve(now, key))
+	assert.Equal(t, 100*time.Millisecond, s.reserve(now, key))
+
+	// no waiting after success
+	s.succeeded(key)
+	assert.Zero(t, s.reserve(now, key))
+}
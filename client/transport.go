@@ -1,7 +1,10 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,50 +14,287 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// maxErrorBodySize limits the size of error response body read for error classification.
+const maxErrorBodySize = 64 * 1024
+
 type transport struct {
 	t *http.Transport
 	l log.Logger
 
-	mRequests  prometheus.Counter
-	mResponses *prometheus.SummaryVec
+	timeout         time.Duration
+	serviceTimeouts map[string]time.Duration
+
+	mRequests      *prometheus.CounterVec
+	mResponses     prometheus.ObserverVec
+	mRequestBytes  *prometheus.CounterVec
+	mResponseBytes *prometheus.CounterVec
+	mErrors        *prometheus.CounterVec
+
+	limiter           *rateLimiter
+	mRateLimitWait    *prometheus.CounterVec
+	mRateLimitDropped *prometheus.CounterVec
+
+	slowdown *slowdown
+	mRetries *prometheus.CounterVec
+
+	breaker *breaker
+
+	pricer *pricer
+	mCost  *prometheus.CounterVec
 }
 
-func newTransport(logger log.Logger) *transport {
+// defaultLatencyBuckets are rds_exporter_request_duration_seconds histogram buckets from 10ms to ~40s.
+var defaultLatencyBuckets = prometheus.ExponentialBuckets(0.01, 2, 13)
+
+func newTransport(logger log.Logger, opts Options, t *http.Transport) *transport {
+	labels := []string{"service", "operation", "region"}
+
+	var responses prometheus.ObserverVec
+	if opts.LegacySummary {
+		responses = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name: "rds_exporter_responses_durations_seconds",
+			Help: "AWS API responses latency distributions.",
+		}, append(labels, "status"))
+	} else {
+		buckets := opts.LatencyBuckets
+		if len(buckets) == 0 {
+			buckets = defaultLatencyBuckets
+		}
+		responses = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:                        "rds_exporter_request_duration_seconds",
+			Help:                        "AWS API requests latency distributions.",
+			Buckets:                     buckets,
+			NativeHistogramBucketFactor: opts.NativeHistogramBucketFactor,
+		}, append(labels, "status"))
+	}
+
 	return &transport{
-		t: &http.Transport{
-			MaxIdleConnsPerHost: 5,
-			IdleConnTimeout:     2 * time.Minute,
-			Proxy:               http.ProxyFromEnvironment,
-		},
+		t: t,
 		l: log.With(logger, "component", "transport"),
 
-		mRequests: prometheus.NewCounter(prometheus.CounterOpts{
+		timeout:         opts.HTTPClient.Timeout,
+		serviceTimeouts: opts.HTTPClient.ServiceTimeouts,
+
+		mRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "rds_exporter_requests_total",
 			Help: "Total number of AWS API requests.",
-		}),
-		mResponses: prometheus.NewSummaryVec(prometheus.SummaryOpts{
-			Name: "rds_exporter_responses_durations_seconds",
-			Help: "AWS API responses latency distributions.",
-		}, []string{"status"}),
+		}, append(labels, "status")),
+		mResponses: responses,
+		mRequestBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_request_bytes_total",
+			Help: "Total size of AWS API request bodies in bytes.",
+		}, labels),
+		mResponseBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_response_bytes_total",
+			Help: "Total size of AWS API response bodies in bytes.",
+		}, labels),
+		mErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_api_errors_total",
+			Help: "Total number of AWS API error responses by error class: throttling, access_denied, not_found, other.",
+		}, append(labels, "class")),
+
+		limiter: newRateLimiter(opts.RateLimits),
+		mRateLimitWait: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_rate_limit_wait_seconds_total",
+			Help: "Total time AWS API requests waited for client-side rate limiter, in seconds.",
+		}, append([]string{"account"}, labels...)),
+		mRateLimitDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_rate_limit_dropped_total",
+			Help: "Total number of AWS API requests dropped by client-side rate limiter.",
+		}, append([]string{"account"}, labels...)),
+
+		slowdown: newSlowdown(opts.Retry),
+		mRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_retries_total",
+			Help: "Total number of AWS API requests retries.",
+		}, labels),
+
+		breaker: newBreaker(opts.CircuitBreaker),
+
+		pricer: newPricer(opts.Prices),
+		mCost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_aws_api_cost_dollars_total",
+			Help: "Estimated total cost of AWS API requests in dollars.",
+		}, []string{"account", "region", "collector"}),
+	}
+}
+
+// collectors returns all transport metrics.
+func (t *transport) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		t.mRequests, t.mResponses, t.mRequestBytes, t.mResponseBytes, t.mErrors,
+		t.mRateLimitWait, t.mRateLimitDropped, t.mRetries, t.breaker.mState, t.mCost,
 	}
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// We could use "net/http/httptrace" package if we ever need more metrics.
 
+	info, err := parseRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	account := accountFrom(req.Context())
+	wait, err := t.limiter.wait(req.Context(), account, info)
+	if wait > 0 {
+		t.mRateLimitWait.WithLabelValues(account, info.service, info.operation, info.region).Add(wait.Seconds())
+	}
+	if err != nil {
+		if _, ok := err.(*rateLimitError); ok {
+			t.mRateLimitDropped.WithLabelValues(account, info.service, info.operation, info.region).Inc()
+			level.Warn(t.l).Log("msg", "Request dropped.", "error", err)
+		}
+		return nil, err
+	}
+
+	// slow down requests to recently throttled APIs
+	key := slowdownKey(account, info.service, info.region)
+	slowdown, err := t.slowdown.wait(req.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if slowdown > 0 {
+		level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s: slowed down for %s.", info.service, info.operation, slowdown))
+	}
+
+	// short-circuit requests to degraded APIs
+	if err = t.breaker.allow(time.Now(), account, info.region, info.service); err != nil {
+		level.Debug(t.l).Log("msg", "Request short-circuited.", "error", err)
+		return nil, err
+	}
+
+	t.mRequestBytes.WithLabelValues(info.service, info.operation, info.region).Add(float64(len(info.body)))
+
+	// timeout covers reading response body, so it is canceled when body is closed
+	parentCtx := req.Context()
+	ctx, cancel := context.WithTimeout(parentCtx, t.timeoutFor(info.service))
+	req = req.WithContext(ctx)
+
 	start := time.Now()
-	t.mRequests.Inc()
 	resp, err := t.t.RoundTrip(req)
 	duration := time.Since(start)
+
+	status := "err"
 	if resp != nil {
-		t.mResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Observe(duration.Seconds())
-		level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s -> %d (%s)", req.Method, req.URL.String(), resp.StatusCode, duration))
-	} else {
-		t.mResponses.WithLabelValues("err").Observe(duration.Seconds())
-		level.Error(t.l).Log("msg", fmt.Sprintf("%s %s -> %s (%s)", req.Method, req.URL.String(), err, duration))
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.mRequests.WithLabelValues(info.service, info.operation, info.region, status).Inc()
+	t.mResponses.WithLabelValues(info.service, info.operation, info.region, status).Observe(duration.Seconds())
+
+	// failures of attempts that will be retried are not counted, so a single failed request counts once
+	failure := resultFailure
+	if retriesLeftFrom(parentCtx) {
+		failure = resultNeutral
+	}
+
+	if resp == nil {
+		level.Error(t.l).Log("msg", fmt.Sprintf("%s %s %s -> %s (%s)", req.Method, req.URL.String(), info.operation, err, duration))
+		cancel()
+		res := failure
+		if parentCtx.Err() != nil {
+			res = resultNeutral
+		}
+		t.breaker.done(time.Now(), account, info.region, info.service, res)
+		return resp, err
+	}
+	level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s %s -> %d (%s)", req.Method, req.URL.String(), info.operation, resp.StatusCode, duration))
+
+	// throttling is handled by rate limits and slowdown, and it is not a failure for circuit breaker
+	res := resultSuccess
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		t.classifyResponse(info, resp)
+		t.slowdown.throttled(key)
+		res = resultNeutral
+	case resp.StatusCode >= 400:
+		switch {
+		case t.classifyResponse(info, resp) == errorThrottling:
+			t.slowdown.throttled(key)
+			res = resultNeutral
+		case resp.StatusCode >= 500:
+			res = failure
+		}
+	default:
+		t.slowdown.succeeded(key)
+	}
+	t.breaker.done(time.Now(), account, info.region, info.service, res)
+
+	body := &countingBody{
+		ReadCloser: resp.Body,
+		c:          t.mResponseBytes.WithLabelValues(info.service, info.operation, info.region),
+		cancel:     cancel,
+	}
+	if resp.StatusCode < 400 {
+		// failed requests are not charged
+		cost, perByte := t.pricer.cost(info)
+		c := t.mCost.WithLabelValues(account, info.region, collectorFrom(req.Context()))
+		c.Add(cost)
+		if perByte > 0 {
+			body.cost = c
+			body.costPerByte = perByte
+		}
 	}
+	resp.Body = body
 	return resp, err
 }
 
+// timeoutFor returns request timeout for given service.
+func (t *transport) timeoutFor(service string) time.Duration {
+	if timeout, ok := t.serviceTimeouts[service]; ok {
+		return timeout
+	}
+	return t.timeout
+}
+
+// classifyResponse reads the beginning of error response body, counts error by class, and returns it.
+// Response body is replaced so it still can be read completely by the caller.
+func (t *transport) classifyResponse(info *requestInfo, resp *http.Response) string {
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body = &multiReadCloser{
+		Reader: io.MultiReader(bytes.NewReader(b), resp.Body),
+		Closer: resp.Body,
+	}
+	if err != nil {
+		level.Warn(t.l).Log("msg", "Failed to read error response body.", "error", err)
+	}
+
+	code := parseErrorCode(resp.Header.Get("X-Amzn-ErrorType"), b)
+	class := classifyError(code, b)
+	level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s: error code %q, class %s", info.service, info.operation, code, class))
+	t.mErrors.WithLabelValues(info.service, info.operation, info.region, class).Inc()
+	return class
+}
+
+// countingBody counts bytes read from response body, and their cost if operation is charged per byte.
+// Request context is canceled when body is closed.
+type countingBody struct {
+	io.ReadCloser
+	c           prometheus.Counter
+	cost        prometheus.Counter
+	costPerByte float64
+	cancel      context.CancelFunc
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.c.Add(float64(n))
+	if b.cost != nil {
+		b.cost.Add(float64(n) * b.costPerByte)
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // check interface
 var _ http.RoundTripper = (*transport)(nil)
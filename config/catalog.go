@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Dimension represents CloudWatch metric dimension.
+type Dimension struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"` // may be empty for DBInstanceIdentifier, DBClusterIdentifier, and Role that are set by exporter
+}
+
+// Metric represents a single CloudWatch metric from metrics catalog file.
+type Metric struct {
+	Namespace      string        `yaml:"namespace"`  // AWS/RDS if empty
+	Name           string        `yaml:"name"`       // CloudWatch metric name
+	Dimensions     []Dimension   `yaml:"dimensions"` // DBInstanceIdentifier if empty
+	Statistics     []string      `yaml:"statistics"` // Average if empty; standard statistics or percentiles like p99
+	Period         time.Duration `yaml:"period"`     // basic.Period if empty
+	Unit           string        `yaml:"unit"`       // may be empty
+	Engines        []string      `yaml:"engines"`    // engine patterns like aurora-mysql or aurora*; all engines if empty
+	PrometheusName string        `yaml:"prometheus_name"`
+	PrometheusHelp string        `yaml:"prometheus_help"` // CloudWatch metric name if empty
+}
+
+// standardStatistics maps CloudWatch standard statistics to Prometheus metric name suffixes.
+var standardStatistics = map[string]string{
+	"Average":     "average",
+	"Maximum":     "maximum",
+	"Minimum":     "minimum",
+	"Sum":         "sum",
+	"SampleCount": "sample_count",
+}
+
+// percentileRE matches CloudWatch extended statistics for percentiles: p50, p99, p99.9, etc.
+var percentileRE = regexp.MustCompile(`^p(\d{1,2})(\.\d+)?$`)
+
+// StatisticSuffix returns Prometheus metric name suffix for given CloudWatch statistic, or empty string if it is not supported.
+func StatisticSuffix(statistic string) string {
+	if suffix, ok := standardStatistics[statistic]; ok {
+		return suffix
+	}
+	if percentileRE.MatchString(statistic) && statistic != "p0" && statistic != "p00" {
+		return strings.ReplaceAll(statistic, ".", "_")
+	}
+	return ""
+}
+
+// PrometheusNames returns Prometheus metric names for all metric statistics.
+// With statistic label, all statistics use PrometheusName without the first statistic's suffix (if any):
+// aws_rds_read_latency. Otherwise, the first statistic uses PrometheusName, and other statistics use it
+// with the first statistic's suffix replaced by their own suffixes:
+// aws_rds_read_latency_average, aws_rds_read_latency_maximum, aws_rds_read_latency_p99.
+func (m Metric) PrometheusNames(statisticLabel bool) []string {
+	statistics := m.Statistics
+	if len(statistics) == 0 {
+		statistics = []string{"Average"}
+	}
+
+	res := make([]string, len(statistics))
+	base := strings.TrimSuffix(m.PrometheusName, "_"+StatisticSuffix(statistics[0]))
+	for i, statistic := range statistics {
+		switch {
+		case statisticLabel:
+			res[i] = base
+		case i == 0:
+			res[i] = m.PrometheusName
+		default:
+			res[i] = base + "_" + StatisticSuffix(statistic)
+		}
+	}
+	return res
+}
+
+// snakeCase converts CloudWatch metric name to snake case: CPUUtilization to cpu_utilization,
+// MaximumUsedTransactionIDs to maximum_used_transaction_ids.
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && startsWord(runes[i+1:])) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// startsWord returns true if runes after an uppercase letter continue a word,
+// and not just pluralize an acronym like IDs.
+func startsWord(rest []rune) bool {
+	if len(rest) == 0 || !unicode.IsLower(rest[0]) {
+		return false
+	}
+	plural := rest[0] == 's' && (len(rest) == 1 || !unicode.IsLower(rest[1]))
+	return !plural
+}
+
+// DefaultPrometheusName returns Prometheus name for Average statistic of AWS/RDS metric:
+// aws_rds_<snake_case name>_average.
+func DefaultPrometheusName(name string) string {
+	return "aws_rds_" + snakeCase(name) + "_average"
+}
+
+// LoadCatalog loads CloudWatch metrics catalog from file.
+// Prometheus names of all statistics are checked for collisions, unless they are distinguished by statistic label.
+func LoadCatalog(filename string, statisticLabel bool) ([]Metric, error) {
+	b, err := os.ReadFile(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog struct {
+		Metrics []Metric `yaml:"metrics"`
+	}
+	if err = yaml.UnmarshalStrict(b, &catalog); err != nil {
+		return nil, err
+	}
+	if len(catalog.Metrics) == 0 {
+		return nil, fmt.Errorf("%s: no metrics", filename)
+	}
+
+	names := make(map[string]string, len(catalog.Metrics)) // Prometheus name => CloudWatch name and statistic
+	for i, m := range catalog.Metrics {
+		if m.Name == "" {
+			return nil, fmt.Errorf("metrics[%d]: name is required", i)
+		}
+		if !model.IsValidMetricName(model.LabelValue(m.PrometheusName)) {
+			return nil, fmt.Errorf("metrics[%d] (%s): invalid prometheus_name %q", i, m.Name, m.PrometheusName)
+		}
+		for j, statistic := range m.Statistics {
+			if StatisticSuffix(statistic) == "" {
+				return nil, fmt.Errorf("metrics[%d] (%s): unsupported statistic %q", i, m.Name, statistic)
+			}
+			for _, other := range m.Statistics[:j] {
+				if other == statistic {
+					return nil, fmt.Errorf("metrics[%d] (%s): duplicate statistic %q", i, m.Name, statistic)
+				}
+			}
+		}
+
+		id := m.Name
+		if statisticLabel {
+			name := m.PrometheusNames(true)[0]
+			if other, ok := names[name]; ok {
+				return nil, fmt.Errorf("metrics[%d] (%s): prometheus name %q is already used by %s", i, id, name, other)
+			}
+			names[name] = id
+		} else {
+			for j, name := range m.PrometheusNames(false) {
+				if len(m.Statistics) > 0 {
+					id = m.Name + " " + m.Statistics[j]
+				}
+				if other, ok := names[name]; ok {
+					return nil, fmt.Errorf("metrics[%d] (%s): prometheus name %q is already used by %s", i, id, name, other)
+				}
+				names[name] = id
+			}
+		}
+
+		auto := make(map[string]bool) // dimensions set by exporter
+		for _, d := range m.Dimensions {
+			if d.Name == "" {
+				return nil, fmt.Errorf("metrics[%d] (%s): dimension name is required", i, m.Name)
+			}
+			if d.Value != "" {
+				continue
+			}
+			switch d.Name {
+			case "DBInstanceIdentifier", "DBClusterIdentifier", "Role":
+				auto[d.Name] = true
+			default:
+				return nil, fmt.Errorf("metrics[%d] (%s): value of dimension %s is required", i, m.Name, d.Name)
+			}
+		}
+		if auto["DBInstanceIdentifier"] && auto["DBClusterIdentifier"] {
+			return nil, fmt.Errorf("metrics[%d] (%s): values of DBInstanceIdentifier and DBClusterIdentifier dimensions can't be both empty", i, m.Name)
+		}
+		if auto["Role"] && !auto["DBClusterIdentifier"] {
+			return nil, fmt.Errorf("metrics[%d] (%s): empty value of Role dimension requires empty value of DBClusterIdentifier dimension", i, m.Name)
+		}
+		for _, e := range m.Engines {
+			if _, err = path.Match(e, ""); err != nil || e == "" {
+				return nil, fmt.Errorf("metrics[%d] (%s): invalid engine pattern %q", i, m.Name, e)
+			}
+		}
+		if m.Period < 0 || m.Period%time.Second != 0 {
+			return nil, fmt.Errorf("metrics[%d] (%s): period should be a positive number of seconds", i, m.Name)
+		}
+	}
+
+	return catalog.Metrics, nil
+}
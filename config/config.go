@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -27,6 +30,10 @@ type Instance struct {
 	DisableEnhancedMetrics bool              `yaml:"disable_enhanced_metrics"`
 	Labels                 map[string]string `yaml:"labels"` // may be empty
 	IRSAEnabled            bool              `yaml:"irsa_enabled"`
+	AccountIDLabel         bool              `yaml:"account_id_label"` // resolve account ID via STS and add account_id label
+	UseFIPSEndpoint        bool              `yaml:"use_fips_endpoint"`
+	UseDualStackEndpoint   bool              `yaml:"use_dualstack_endpoint"`
+	STSRegionalEndpoint    bool              `yaml:"sts_regional_endpoint"` // use regional STS endpoint instead of global one
 
 	// TODO Type InstanceType `yaml:"type"` // may be empty for old pmm-managed
 }
@@ -40,9 +47,97 @@ func (i Instance) String() string {
 	return res
 }
 
+// RateLimit represents client-side AWS API rate limit.
+// Empty account, region, service and operation match any value;
+// the limit is applied separately for each account, region, service and operation.
+type RateLimit struct {
+	Account   string        `yaml:"account"`
+	Region    string        `yaml:"region"`
+	Service   string        `yaml:"service"`   // endpoint prefix: monitoring, logs, rds, sts
+	Operation string        `yaml:"operation"` // GetMetricStatistics, FilterLogEvents, etc.
+	Rate      float64       `yaml:"rate"`      // requests per second
+	Burst     int           `yaml:"burst"`
+	MaxWait   time.Duration `yaml:"max_wait"` // requests that would wait longer are dropped; 0 means no limit
+}
+
+// Retry represents AWS API requests retry policy.
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts"` // including the first attempt
+	BaseBackoff time.Duration `yaml:"base_backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	Jitter      float64       `yaml:"jitter"` // randomized fraction of backoff, from 0 to 1
+}
+
+// DefaultRetry is used for fields missing in configuration file.
+var DefaultRetry = Retry{
+	MaxAttempts: 4,
+	BaseBackoff: 100 * time.Millisecond,
+	MaxBackoff:  20 * time.Second,
+	Jitter:      0.5,
+}
+
+// CircuitBreaker represents AWS API circuit breaker settings.
+type CircuitBreaker struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // consecutive failures to open circuit; 0 disables circuit breaker
+	OpenTimeout      time.Duration `yaml:"open_timeout"`      // time before a probe request is allowed
+}
+
+// DefaultCircuitBreaker is used for fields missing in configuration file; circuit breaker is disabled by default.
+var DefaultCircuitBreaker = CircuitBreaker{
+	OpenTimeout: time.Minute,
+}
+
+// HTTPClient represents HTTP client settings for AWS API requests.
+type HTTPClient struct {
+	Timeout             time.Duration            `yaml:"timeout"`          // per request attempt, including reading response body
+	ServiceTimeouts     map[string]time.Duration `yaml:"service_timeouts"` // endpoint prefix (monitoring, logs, rds, sts) => timeout
+	ProxyURL            string                   `yaml:"proxy_url"`        // HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used if empty
+	NoProxy             []string                 `yaml:"no_proxy"`         // hosts, domains, IP addresses and CIDRs that are accessed directly
+	CABundle            string                   `yaml:"ca_bundle"`        // PEM file with additional trusted CA certificates
+	MaxIdleConns        int                      `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int                      `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int                      `yaml:"max_conns_per_host"` // 0 means no limit
+	IdleConnTimeout     time.Duration            `yaml:"idle_conn_timeout"`
+}
+
+// DefaultHTTPClient is used for fields missing in configuration file.
+var DefaultHTTPClient = HTTPClient{
+	Timeout:             15 * time.Second,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 5,
+	IdleConnTimeout:     2 * time.Minute,
+}
+
+// Basic represents basic metrics collector settings.
+type Basic struct {
+	CatalogFile       string        `yaml:"catalog_file"`       // relative to configuration file; built-in metrics are used if empty
+	StatisticLabel    bool          `yaml:"statistic_label"`    // expose statistics with statistic label instead of name suffixes
+	Timestamps        bool          `yaml:"timestamps"`         // expose datapoints with their CloudWatch timestamps
+	AllDatapoints     bool          `yaml:"all_datapoints"`     // expose all datapoints in the window instead of the latest one; implies timestamps
+	Workers           int           `yaml:"workers"`            // concurrent GetMetricData requests for all instances
+	ScrapeTimeout     time.Duration `yaml:"scrape_timeout"`     // deadline of a single refresh of all instances
+	Discovery         bool          `yaml:"discovery"`          // request only AWS/RDS instance metrics found by ListMetrics
+	DiscoveryInterval time.Duration `yaml:"discovery_interval"` // time between ListMetrics discoveries
+
+	Metrics []Metric `yaml:"-"` // loaded from catalog file
+}
+
+// DefaultBasic is used for fields missing in configuration file.
+var DefaultBasic = Basic{
+	Workers:           5,
+	ScrapeTimeout:     time.Minute,
+	DiscoveryInterval: 15 * time.Minute,
+}
+
 // Config contains configuration file information.
 type Config struct {
-	Instances []Instance `yaml:"instances"`
+	Instances      []Instance     `yaml:"instances"`
+	RateLimits     []RateLimit    `yaml:"rate_limits"`
+	Retry          Retry          `yaml:"retry"`
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker"`
+	HTTPClient     HTTPClient     `yaml:"http_client"`
+	Basic          Basic          `yaml:"basic"`
+	Transforms     []Transform    `yaml:"transforms"` // applied after default rules
 }
 
 // Load loads configuration from file.
@@ -52,10 +147,67 @@ func Load(filename string) (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
+	config := Config{
+		Retry:          DefaultRetry,
+		CircuitBreaker: DefaultCircuitBreaker,
+		HTTPClient:     DefaultHTTPClient,
+		Basic:          DefaultBasic,
+	}
 	if err = yaml.Unmarshal(b, &config); err != nil {
 		return nil, err
 	}
 
+	for i, l := range config.RateLimits {
+		if l.Rate <= 0 {
+			return nil, fmt.Errorf("rate_limits[%d]: rate should be positive", i)
+		}
+	}
+
+	r := config.Retry
+	if r.MaxAttempts < 1 {
+		return nil, fmt.Errorf("retry: max_attempts should be positive")
+	}
+	if r.BaseBackoff <= 0 || r.MaxBackoff < r.BaseBackoff {
+		return nil, fmt.Errorf("retry: base_backoff should be positive and not greater than max_backoff")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return nil, fmt.Errorf("retry: jitter should be between 0 and 1")
+	}
+
+	if cb := config.CircuitBreaker; cb.FailureThreshold < 0 || (cb.FailureThreshold > 0 && cb.OpenTimeout <= 0) {
+		return nil, fmt.Errorf("circuit_breaker: failure_threshold should not be negative, and open_timeout should be positive")
+	}
+
+	h := config.HTTPClient
+	if h.Timeout <= 0 {
+		return nil, fmt.Errorf("http_client: timeout should be positive")
+	}
+	for service, timeout := range h.ServiceTimeouts {
+		if timeout <= 0 {
+			return nil, fmt.Errorf("http_client: service_timeouts[%s] should be positive", service)
+		}
+	}
+	if h.MaxIdleConns < 0 || h.MaxIdleConnsPerHost < 0 || h.MaxConnsPerHost < 0 {
+		return nil, fmt.Errorf("http_client: connection pool sizes should not be negative")
+	}
+
+	for i, t := range config.Transforms {
+		if err = t.Check(); err != nil {
+			return nil, fmt.Errorf("transforms[%d]: %w", i, err)
+		}
+	}
+
+	if b := config.Basic; b.Workers <= 0 || b.ScrapeTimeout <= 0 || b.DiscoveryInterval <= 0 {
+		return nil, fmt.Errorf("basic: workers, scrape_timeout, and discovery_interval should be positive")
+	}
+	if f := config.Basic.CatalogFile; f != "" {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(filepath.Dir(filename), f)
+		}
+		if config.Basic.Metrics, err = LoadCatalog(f, config.Basic.StatisticLabel); err != nil {
+			return nil, fmt.Errorf("basic: %w", err)
+		}
+	}
+
 	return &config, nil
 }
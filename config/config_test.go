@@ -0,0 +1,154 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	golden    = flag.Bool("golden", false, "does nothing; exists only for compatibility with other packages")
+	goldenTXT = flag.Bool("golden-txt", false, "does nothing; exists only for compatibility with other packages")
+)
+
+func TestLoadCatalog(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(filename, []byte("basic:\n  catalog_file: metrics.yml\n"), 0o600))
+	b, err := os.ReadFile(filepath.Join("testdata", "catalog.yml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metrics.yml"), b, 0o600))
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+	expected := []Metric{{
+		Name:           "CPUUtilization",
+		Statistics:     []string{"Maximum"},
+		Period:         5 * time.Minute,
+		Unit:           "Percent",
+		PrometheusName: "aws_rds_cpu_utilization_maximum",
+		PrometheusHelp: "The maximum percentage of CPU utilization.",
+	}, {
+		Namespace:      "Custom/RDS",
+		Name:           "QueueDepth",
+		Dimensions:     []Dimension{{Name: "DBInstanceIdentifier"}, {Name: "Environment", Value: "production"}},
+		Engines:        []string{"mysql", "aurora*"},
+		PrometheusName: "custom_rds_queue_depth",
+	}}
+	assert.Equal(t, expected, cfg.Basic.Metrics)
+
+	for name, catalog := range map[string]string{
+		"collision":      "metrics: [{name: A, prometheus_name: a}, {name: B, prometheus_name: a}]",
+		"invalid name":   "metrics: [{name: A, prometheus_name: a-b}]",
+		"no dimension":   "metrics: [{name: A, prometheus_name: a, dimensions: [{name: Env}]}]",
+		"both ids":       "metrics: [{name: A, prometheus_name: a, dimensions: [{name: DBInstanceIdentifier}, {name: DBClusterIdentifier}]}]",
+		"role":           "metrics: [{name: A, prometheus_name: a, dimensions: [{name: DBInstanceIdentifier}, {name: Role}]}]",
+		"unknown field":  "metrics: [{name: A, prometheus_name: a, statistic: Sum}]",
+		"engine":         "metrics: [{name: A, prometheus_name: a, engines: ['aurora[']}]",
+		"partial period": "metrics: [{name: A, prometheus_name: a, period: 1500ms}]",
+		"statistic":      "metrics: [{name: A, prometheus_name: a, statistics: [Median]}]",
+		"percentile":     "metrics: [{name: A, prometheus_name: a, statistics: [p100]}]",
+		"duplicate":      "metrics: [{name: A, prometheus_name: a, statistics: [Sum, Sum]}]",
+		"suffix":         "metrics: [{name: A, prometheus_name: a_average, statistics: [Average, Maximum]}, {name: B, prometheus_name: a_maximum}]",
+	} {
+		f := filepath.Join(dir, "catalog.yml")
+		require.NoError(t, os.WriteFile(f, []byte(catalog), 0o600))
+		_, err = LoadCatalog(f, false)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPrometheusNames(t *testing.T) {
+	m := Metric{
+		Name:           "ReadLatency",
+		Statistics:     []string{"Average", "Maximum", "SampleCount", "p99", "p99.9"},
+		PrometheusName: "aws_rds_read_latency_average",
+	}
+	assert.Equal(t, []string{
+		"aws_rds_read_latency_average",
+		"aws_rds_read_latency_maximum",
+		"aws_rds_read_latency_sample_count",
+		"aws_rds_read_latency_p99",
+		"aws_rds_read_latency_p99_9",
+	}, m.PrometheusNames(false))
+	assert.Equal(t, []string{
+		"aws_rds_read_latency",
+		"aws_rds_read_latency",
+		"aws_rds_read_latency",
+		"aws_rds_read_latency",
+		"aws_rds_read_latency",
+	}, m.PrometheusNames(true))
+
+	m = Metric{Name: "ReplicaLag", PrometheusName: "aws_rds_replica_lag", Statistics: []string{"Average", "Maximum"}}
+	assert.Equal(t, []string{"aws_rds_replica_lag", "aws_rds_replica_lag_maximum"}, m.PrometheusNames(false))
+
+	assert.Equal(t, []string{"aws_rds_replica_lag", "aws_rds_replica_lag"}, m.PrometheusNames(true))
+
+	m = Metric{Name: "ReplicaLag", PrometheusName: "aws_rds_replica_lag"}
+	assert.Equal(t, []string{"aws_rds_replica_lag"}, m.PrometheusNames(false))
+
+	dir := t.TempDir()
+	f := filepath.Join(dir, "catalog.yml")
+	catalog := "metrics: [{name: A, prometheus_name: a_average, statistics: [Average, Maximum]}, {name: B, prometheus_name: a_maximum}]"
+	require.NoError(t, os.WriteFile(f, []byte(catalog), 0o600))
+	_, err := LoadCatalog(f, true)
+	assert.NoError(t, err)
+
+	// names without suffixes collide only with statistic label
+	catalog = "metrics: [{name: A, prometheus_name: a_average}, {name: B, prometheus_name: a_sum, statistics: [Sum]}]"
+	require.NoError(t, os.WriteFile(f, []byte(catalog), 0o600))
+	_, err = LoadCatalog(f, false)
+	assert.NoError(t, err)
+	_, err = LoadCatalog(f, true)
+	assert.EqualError(t, err, `metrics[1] (B): prometheus name "a" is already used by A`)
+}
+
+func TestSnakeCase(t *testing.T) {
+	for s, expected := range map[string]string{
+		"CPUUtilization":            "cpu_utilization",
+		"CPUCreditBalance":          "cpu_credit_balance",
+		"ReadIOPS":                  "read_iops",
+		"VolumeReadIOPs":            "volume_read_iops",
+		"DDLLatency":                "ddl_latency",
+		"MaximumUsedTransactionIDs": "maximum_used_transaction_ids",
+		"DBLoadNonCPU":              "db_load_non_cpu",
+	} {
+		assert.Equal(t, expected, snakeCase(s), s)
+	}
+}
+
+func TestLoadTransforms(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yml")
+	config := "transforms:\n" +
+		"  - {collector: basic, cloudwatch_metric: FreeableMemory, scale: 0.001, rename: aws_rds_freeable_memory_kilobytes}\n" +
+		"  - {metric: node_memory_*_bytes, offset: -1}\n"
+	require.NoError(t, os.WriteFile(filename, []byte(config), 0o600))
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+	expected := []Transform{
+		{Collector: "basic", CloudWatchMetric: "FreeableMemory", Scale: 0.001, Rename: "aws_rds_freeable_memory_kilobytes"},
+		{Metric: "node_memory_*_bytes", Offset: -1},
+	}
+	assert.Equal(t, expected, cfg.Transforms)
+
+	for name, transform := range map[string]string{
+		"collector":  "{collector: proxy, metric: a}",
+		"no metric":  "{collector: basic, scale: 2}",
+		"cloudwatch": "{collector: enhanced, cloudwatch_metric: EngineUptime}",
+		"statistic":  "{collector: enhanced, metric: a, statistic: Average}",
+		"pattern":    "{metric: 'node_['}",
+		"rename":     "{metric: a, rename: a-b}",
+		"keep":       "{metric: a, keep: true}",
+	} {
+		require.NoError(t, os.WriteFile(filename, []byte("transforms: ["+transform+"]\n"), 0o600))
+		_, err = Load(filename)
+		assert.Error(t, err, name)
+	}
+}
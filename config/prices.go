@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Price units.
+const (
+	PerRequest  = "request"  // price per API request
+	PerMetric   = "metric"   // price per metric requested (GetMetricData)
+	PerGigabyte = "gigabyte" // price per gigabyte of response data
+)
+
+// Price represents AWS API price for a single operation.
+type Price struct {
+	Service   string  `yaml:"service"`   // endpoint prefix: monitoring, logs, rds, sts
+	Operation string  `yaml:"operation"` // GetMetricStatistics, FilterLogEvents, etc.
+	Unit      string  `yaml:"unit"`      // request, metric, or gigabyte
+	Price     float64 `yaml:"price"`     // in dollars per unit
+}
+
+// DefaultPrices are AWS API prices in us-east-1 region.
+// See https://aws.amazon.com/cloudwatch/pricing/.
+var DefaultPrices = []Price{
+	{Service: "monitoring", Operation: "GetMetricStatistics", Unit: PerRequest, Price: 0.01 / 1000},
+	{Service: "monitoring", Operation: "ListMetrics", Unit: PerRequest, Price: 0.01 / 1000},
+	{Service: "monitoring", Operation: "GetMetricData", Unit: PerMetric, Price: 0.01 / 1000},
+	{Service: "logs", Operation: "FilterLogEvents", Unit: PerGigabyte, Price: 0.005},
+}
+
+// LoadPrices loads price table from file.
+func LoadPrices(filename string) ([]Price, error) {
+	b, err := os.ReadFile(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var table struct {
+		Prices []Price `yaml:"prices"`
+	}
+	if err = yaml.Unmarshal(b, &table); err != nil {
+		return nil, err
+	}
+
+	for i, p := range table.Prices {
+		switch p.Unit {
+		case PerRequest, PerMetric, PerGigabyte:
+		default:
+			return nil, fmt.Errorf("prices[%d]: unexpected unit %q", i, p.Unit)
+		}
+	}
+
+	return table.Prices, nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/prometheus/common/model"
+)
+
+// Transform represents a single metric transformation rule from configuration file.
+// Operations are applied in the following order: now_minus, scale, offset, rate_to_counter, rename.
+// With keep option, they are applied to a copy of the metric, and the original metric is kept unchanged.
+type Transform struct {
+	Collector        string  `yaml:"collector"`         // basic or enhanced; both if empty
+	Metric           string  `yaml:"metric"`            // Prometheus metric name pattern like node_memory_*; any if empty
+	CloudWatchMetric string  `yaml:"cloudwatch_metric"` // CloudWatch metric name; basic collector only
+	Statistic        string  `yaml:"statistic"`         // CloudWatch statistic like Average or p99; basic collector only
+	NowMinus         bool    `yaml:"now_minus"`         // replace value with sample timestamp minus value, in UNIX seconds
+	Scale            float64 `yaml:"scale"`             // multiplier; 1 if zero
+	Offset           float64 `yaml:"offset"`            // added after scaling
+	RateToCounter    bool    `yaml:"rate_to_counter"`   // integrate per-second rate over time into a counter
+	Rename           string  `yaml:"rename"`            // new Prometheus metric name; not changed if empty
+	Keep             bool    `yaml:"keep"`              // keep the original metric and expose the result as a new one; requires rename
+}
+
+// Check returns an error if rule is invalid.
+func (t Transform) Check() error {
+	switch t.Collector {
+	case "", "basic", "enhanced":
+		// nothing
+	default:
+		return fmt.Errorf("unexpected collector %q", t.Collector)
+	}
+	if t.Metric == "" && t.CloudWatchMetric == "" {
+		return fmt.Errorf("metric or cloudwatch_metric is required")
+	}
+	if t.CloudWatchMetric != "" && t.Collector != "basic" {
+		return fmt.Errorf("cloudwatch_metric requires basic collector")
+	}
+	if t.Statistic != "" && t.Collector != "basic" {
+		return fmt.Errorf("statistic requires basic collector")
+	}
+	if _, err := path.Match(t.Metric, ""); err != nil {
+		return fmt.Errorf("invalid metric pattern %q: %w", t.Metric, err)
+	}
+	if t.Rename != "" && !model.IsValidMetricName(model.LabelValue(t.Rename)) {
+		return fmt.Errorf("invalid rename %q", t.Rename)
+	}
+	if t.Keep && t.Rename == "" {
+		return fmt.Errorf("keep requires rename")
+	}
+	return nil
+}
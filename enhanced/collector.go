@@ -11,6 +11,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/duyhai-bic/rds_exporter/sessions"
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
 // Collector collects enhanced RDS metrics by utilizing several scrapers.
@@ -21,8 +22,21 @@ type Collector struct {
 	rw         sync.RWMutex
 	metrics    map[string][]prometheus.Metric
 	cancelFunc context.CancelFunc
+	capacity   CapacitySource   // nil if not set
+	transforms *transform.Rules // applied by scrapers
 }
 
+// CapacitySource provides the current capacity of Aurora Serverless v2 instances.
+type CapacitySource interface {
+	// Capacity returns the current capacity of given instance in ACUs, and false if it is unknown.
+	Capacity(instance *sessions.Instance) (float64, bool)
+}
+
+const (
+	capacityName = "rdsosmetrics_General_serverlessDatabaseCapacity"
+	capacityHelp = "The current capacity of Aurora Serverless v2 DB instance, in ACUs."
+)
+
 // Maximal and minimal metrics update interval.
 const (
 	maxInterval = 60 * time.Second
@@ -30,19 +44,30 @@ const (
 )
 
 // NewCollector creates new collector and starts scrapers.
-func NewCollector(sessions *sessions.Sessions, logger log.Logger) *Collector {
+// Given transformation rules are applied to metrics as they are scraped; default rules are used if nil.
+func NewCollector(sessions *sessions.Sessions, transforms *transform.Rules, logger log.Logger) *Collector {
+	if transforms == nil {
+		transforms = transform.New(nil)
+	}
 	c := &Collector{
-		sessions: sessions,
-		logger:   log.With(logger, "component", "enhanced"),
-		metrics:  make(map[string][]prometheus.Metric),
+		sessions:   sessions,
+		logger:     log.With(logger, "component", "enhanced"),
+		metrics:    make(map[string][]prometheus.Metric),
+		transforms: transforms,
 	}
+	c.start(sessions, logger)
+	return c
+}
+
+// start starts scrapers for given sessions that save metrics to collector.
+func (c *Collector) start(sessions *sessions.Sessions, logger log.Logger) {
 	// Create a cancellable context for all goroutines in this collector.
 	ctx, cancel := context.WithCancel(context.Background())
 	c.cancelFunc = cancel
 
 	for session, instances := range sessions.AllSessions() {
 		enabledInstances := getEnabledInstances(instances)
-		s := newScraper(session, enabledInstances, logger)
+		s := newScraper(session, enabledInstances, c.transforms, logger)
 
 		interval := maxInterval
 		for _, instance := range enabledInstances {
@@ -81,8 +106,6 @@ func NewCollector(sessions *sessions.Sessions, logger log.Logger) *Collector {
 		}()
 		go s.start(ctx, interval, ch)
 	}
-
-	return c
 }
 
 func getEnabledInstances(instances []sessions.Instance) []sessions.Instance {
@@ -106,15 +129,26 @@ func (c *Collector) setMetrics(m map[string][]prometheus.Metric) {
 	c.rw.Unlock()
 }
 
+// SetCapacitySource sets source of Aurora Serverless v2 instances capacity that is exposed with their enhanced metrics.
+func (c *Collector) SetCapacitySource(source CapacitySource) {
+	c.rw.Lock()
+	c.capacity = source
+	c.rw.Unlock()
+}
+
+// Update restarts scrapers for given sessions, keeping transformation rules.
 func (c *Collector) Update(sessions *sessions.Sessions, logger log.Logger) {
 	level.Info(c.logger).Log("msg", "Canceling the old enhanced monitoring scraper")
 	c.cancelFunc()
 	level.Info(c.logger).Log("msg", "The old enhanced monitoring scraper has been canceled")
-	newCollector := NewCollector(sessions, logger)
-	c.cancelFunc = newCollector.cancelFunc
-	c.logger = newCollector.logger
-	c.sessions = newCollector.sessions
-	c.metrics = newCollector.metrics
+	c.rw.Lock()
+	c.logger = log.With(logger, "component", "enhanced")
+	c.sessions = sessions
+	c.metrics = make(map[string][]prometheus.Metric)
+	c.rw.Unlock()
+
+	// keep rules, so counters made from rates continue
+	c.start(sessions, logger)
 }
 
 // Describe implements prometheus.Collector.
@@ -132,6 +166,53 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 			ch <- m
 		}
 	}
+
+	c.collectCapacity(ch)
+}
+
+// collectCapacity sends the current capacity of Aurora Serverless v2 instances with enhanced metrics,
+// so their CPU utilization can be interpreted.
+func (c *Collector) collectCapacity(ch chan<- prometheus.Metric) {
+	if c.capacity == nil || c.sessions == nil {
+		return
+	}
+
+	for _, instances := range c.sessions.AllSessions() {
+		for _, instance := range instances {
+			if len(c.metrics[instance.ResourceID]) == 0 {
+				continue
+			}
+			if m := capacityMetric(c.capacity, instance); m != nil {
+				ch <- m
+			}
+		}
+	}
+}
+
+// capacityMetric returns the current capacity metric of given instance,
+// or nil if instance is not Aurora Serverless v2 or its capacity is unknown.
+func capacityMetric(source CapacitySource, instance sessions.Instance) prometheus.Metric {
+	if !instance.Serverless() {
+		return nil
+	}
+	v, ok := source.Capacity(&instance)
+	if !ok {
+		return nil
+	}
+
+	labels := prometheus.Labels{
+		"region":              instance.Region,
+		"instance_identifier": instance.Instance,
+	}
+	for n, v := range instance.MetricLabels() {
+		if v == "" {
+			delete(labels, n)
+		} else {
+			labels[n] = v
+		}
+	}
+	desc := prometheus.NewDesc(capacityName, capacityHelp, nil, labels)
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
 }
 
 // check interfaces
@@ -0,0 +1,83 @@
+package enhanced
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duyhai-bic/rds_exporter/client"
+	"github.com/duyhai-bic/rds_exporter/sessions"
+)
+
+type capacities map[string]float64
+
+func (c capacities) Capacity(instance *sessions.Instance) (float64, bool) {
+	v, ok := c[instance.Region+"/"+instance.Instance]
+	return v, ok
+}
+
+func TestCapacityMetric(t *testing.T) {
+	source := capacities{"us-east-1/db1": 2.5, "us-east-1/db2": 4}
+
+	m := capacityMetric(source, sessions.Instance{
+		Region:        "us-east-1",
+		Instance:      "db1",
+		InstanceClass: "db.serverless",
+		Labels:        map[string]string{"env": "prod"},
+	})
+	require.NotNil(t, m)
+	assert.Contains(t, m.Desc().String(), `"rdsosmetrics_General_serverlessDatabaseCapacity"`)
+	var pb dto.Metric
+	require.NoError(t, m.Write(&pb))
+	labels := make(map[string]string)
+	for _, l := range pb.Label {
+		labels[l.GetName()] = l.GetValue()
+	}
+	assert.Equal(t, map[string]string{"env": "prod", "instance_identifier": "db1", "region": "us-east-1"}, labels)
+	assert.Equal(t, 2.5, pb.GetGauge().GetValue())
+
+	// provisioned instance
+	assert.Nil(t, capacityMetric(source, sessions.Instance{Region: "us-east-1", Instance: "db2", InstanceClass: "db.r6g.large"}))
+
+	// unknown capacity
+	assert.Nil(t, capacityMetric(source, sessions.Instance{Region: "us-east-1", Instance: "db3", InstanceClass: "db.serverless"}))
+}
+
+func TestCollectorUpdate(t *testing.T) {
+	logger := promlog.New(&promlog.Config{})
+	client, err := client.New(logger, client.Options{})
+	require.NoError(t, err)
+	sess, err := sessions.New(nil, client, logger, sessions.TraceOptions{})
+	require.NoError(t, err)
+
+	c := NewCollector(sess, nil, logger)
+	c.SetCapacitySource(capacities{})
+	defer c.cancelFunc()
+
+	// collect concurrently with updates; run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			c.Update(sess, logger)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		ch := make(chan prometheus.Metric)
+		go func() {
+			c.Collect(ch)
+			close(ch)
+		}()
+		for range ch {
+		}
+	}
+}
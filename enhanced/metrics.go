@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
 // osMetrics represents available Enhanced Monitoring OS metrics from CloudWatch Logs.
@@ -102,22 +105,22 @@ type loadAverageMinute struct {
 
 //nolint:lll
 type memory struct {
-	Active         int `json:"active"         node:"Active_bytes"       m:"1024" help:"The amount of assigned memory, in kilobytes."`
-	Buffers        int `json:"buffers"        node:"Buffers_bytes"      m:"1024" help:"The amount of memory used for buffering I/O requests prior to writing to the storage device, in kilobytes."`
-	Cached         int `json:"cached"         node:"Cached_bytes"       m:"1024" help:"The amount of memory used for caching file system–based I/O."`
-	Dirty          int `json:"dirty"          node:"Dirty_bytes"        m:"1024" help:"The amount of memory pages in RAM that have been modified but not written to their related data block in storage, in kilobytes."`
-	Free           int `json:"free"           node:"MemFree_bytes"      m:"1024" help:"The amount of unassigned memory, in kilobytes."`
-	HugePagesFree  int `json:"hugePagesFree"  node:"HugePages_Free"     m:"1"    help:"The number of free huge pages. Huge pages are a feature of the Linux kernel."`
-	HugePagesRsvd  int `json:"hugePagesRsvd"  node:"HugePages_Rsvd"     m:"1"    help:"The number of committed huge pages."`
-	HugePagesSize  int `json:"hugePagesSize"  node:"Hugepagesize_bytes" m:"1024" help:"The size for each huge pages unit, in kilobytes."`
-	HugePagesSurp  int `json:"hugePagesSurp"  node:"HugePages_Surp"     m:"1"    help:"The number of available surplus huge pages over the total."`
-	HugePagesTotal int `json:"hugePagesTotal" node:"HugePages_Total"    m:"1"    help:"The total number of huge pages for the system."`
-	Inactive       int `json:"inactive"       node:"Inactive_bytes"     m:"1024" help:"The amount of least-frequently used memory pages, in kilobytes."`
-	Mapped         int `json:"mapped"         node:"Mapped_bytes"       m:"1024" help:"The total amount of file-system contents that is memory mapped inside a process address space, in kilobytes."`
-	PageTables     int `json:"pageTables"     node:"PageTables_bytes"   m:"1024" help:"The amount of memory used by page tables, in kilobytes."`
-	Slab           int `json:"slab"           node:"Slab_bytes"         m:"1024" help:"The amount of reusable kernel data structures, in kilobytes."`
-	Total          int `json:"total"          node:"MemTotal_bytes"     m:"1024" help:"The total amount of memory, in kilobytes."`
-	Writeback      int `json:"writeback"      node:"Writeback_bytes"    m:"1024" help:"The amount of dirty pages in RAM that are still being written to the backing storage, in kilobytes."`
+	Active         int `json:"active"         node:"Active_bytes"       help:"The amount of assigned memory, in kilobytes."`
+	Buffers        int `json:"buffers"        node:"Buffers_bytes"      help:"The amount of memory used for buffering I/O requests prior to writing to the storage device, in kilobytes."`
+	Cached         int `json:"cached"         node:"Cached_bytes"       help:"The amount of memory used for caching file system–based I/O."`
+	Dirty          int `json:"dirty"          node:"Dirty_bytes"        help:"The amount of memory pages in RAM that have been modified but not written to their related data block in storage, in kilobytes."`
+	Free           int `json:"free"           node:"MemFree_bytes"      help:"The amount of unassigned memory, in kilobytes."`
+	HugePagesFree  int `json:"hugePagesFree"  node:"HugePages_Free"     help:"The number of free huge pages. Huge pages are a feature of the Linux kernel."`
+	HugePagesRsvd  int `json:"hugePagesRsvd"  node:"HugePages_Rsvd"     help:"The number of committed huge pages."`
+	HugePagesSize  int `json:"hugePagesSize"  node:"Hugepagesize_bytes" help:"The size for each huge pages unit, in kilobytes."`
+	HugePagesSurp  int `json:"hugePagesSurp"  node:"HugePages_Surp"     help:"The number of available surplus huge pages over the total."`
+	HugePagesTotal int `json:"hugePagesTotal" node:"HugePages_Total"    help:"The total number of huge pages for the system."`
+	Inactive       int `json:"inactive"       node:"Inactive_bytes"     help:"The amount of least-frequently used memory pages, in kilobytes."`
+	Mapped         int `json:"mapped"         node:"Mapped_bytes"       help:"The total amount of file-system contents that is memory mapped inside a process address space, in kilobytes."`
+	PageTables     int `json:"pageTables"     node:"PageTables_bytes"   help:"The amount of memory used by page tables, in kilobytes."`
+	Slab           int `json:"slab"           node:"Slab_bytes"         help:"The amount of reusable kernel data structures, in kilobytes."`
+	Total          int `json:"total"          node:"MemTotal_bytes"     help:"The total amount of memory, in kilobytes."`
+	Writeback      int `json:"writeback"      node:"Writeback_bytes"    help:"The amount of dirty pages in RAM that are still being written to the backing storage, in kilobytes."`
 }
 
 type network struct {
@@ -143,13 +146,13 @@ type processList struct {
 
 //nolint:lll
 type swap struct {
-	Cached float64 `json:"cached" node:"node_memory_SwapCached_bytes" m:"1024" help:"The amount of swap memory, in kilobytes, used as cache memory."  nodehelp:"Memory information field SwapCached."`
-	Free   float64 `json:"free"   node:"node_memory_SwapFree_bytes"   m:"1024" help:"The total amount of swap memory free, in kilobytes."             nodehelp:"Memory information field SwapFree."`
-	Total  float64 `json:"total"  node:"node_memory_SwapTotal_bytes"  m:"1024" help:"The total amount of swap memory available, in kilobytes."        nodehelp:"Memory information field SwapTotal."`
+	Cached float64 `json:"cached" node:"node_memory_SwapCached_bytes" help:"The amount of swap memory, in kilobytes, used as cache memory."  nodehelp:"Memory information field SwapCached."`
+	Free   float64 `json:"free"   node:"node_memory_SwapFree_bytes"   help:"The total amount of swap memory free, in kilobytes."             nodehelp:"Memory information field SwapFree."`
+	Total  float64 `json:"total"  node:"node_memory_SwapTotal_bytes"  help:"The total amount of swap memory available, in kilobytes."        nodehelp:"Memory information field SwapTotal."`
 
-	// we use multiplier 0.25 to convert a number of kilobytes to a number of 4k pages (what our dashboards assume)
-	In  float64 `json:"in"  node:"node_vmstat_pswpin"  m:"0.25" help:"The total amount of memory, in kilobytes, swapped in from disk." nodehelp:"/proc/vmstat information field pswpin"`
-	Out float64 `json:"out" node:"node_vmstat_pswpout" m:"0.25" help:"The total amount of memory, in kilobytes, swapped out to disk."  nodehelp:"/proc/vmstat information field pswpout"`
+	// transform.Defaults convert a number of kilobytes to a number of 4k pages (what our dashboards assume)
+	In  float64 `json:"in"  node:"node_vmstat_pswpin"  help:"The total amount of memory, in kilobytes, swapped in from disk." nodehelp:"/proc/vmstat information field pswpin"`
+	Out float64 `json:"out" node:"node_vmstat_pswpout" help:"The total amount of memory, in kilobytes, swapped out to disk."  nodehelp:"/proc/vmstat information field pswpout"`
 }
 
 type tasks struct {
@@ -175,8 +178,75 @@ func parseOSMetrics(b []byte, disallowUnknownFields bool) (*osMetrics, error) {
 	return &m, nil
 }
 
-// makeGauge returns Prometheus gauge for given reflect.Value.
-func makeGauge(desc *prometheus.Desc, labelValues []string, value reflect.Value) prometheus.Metric {
+// parseUptime parses uptime like "01:45:58" or "1 day, 07:11:58".
+func parseUptime(s string) (time.Duration, error) {
+	var days int
+	if i := strings.Index(s, ", "); i >= 0 {
+		if _, err := fmt.Sscanf(s[:i], "%d day", &days); err != nil {
+			return 0, fmt.Errorf("failed to parse uptime %q: %w", s, err)
+		}
+		s = s[i+2:]
+	}
+
+	var h, m, sec int
+	if _, err := fmt.Sscanf(s, "%d:%d:%d", &h, &m, &sec); err != nil {
+		return 0, fmt.Errorf("failed to parse uptime %q: %w", s, err)
+	}
+	d := time.Duration(days)*24*time.Hour + time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	return d + time.Duration(sec)*time.Second, nil
+}
+
+// bootTime returns instance boot time, or zero time if uptime can't be parsed.
+func (m *osMetrics) bootTime() time.Time {
+	uptime, err := parseUptime(m.Uptime)
+	if err != nil {
+		return time.Time{}
+	}
+	return m.Timestamp.Add(-uptime)
+}
+
+// builder makes Prometheus metrics for a single OS metrics sample, applying transformation rules to them.
+type builder struct {
+	constLabels prometheus.Labels
+	rules       *transform.Rules // default rules if nil
+	timestamp   time.Time
+	bootTime    time.Time // zero if unknown
+}
+
+// metrics returns metrics for a single value: the original one, or several if transformation rules keep it.
+// Metric type is changed to counter by rate_to_counter rules.
+func (b *builder) metrics(name, help string, valueType prometheus.ValueType, value float64, labelKeys, labelValues []string) []prometheus.Metric {
+	labels := make(map[string]string, len(b.constLabels)+len(labelKeys))
+	for n, v := range b.constLabels {
+		labels[n] = v
+	}
+	for i, n := range labelKeys {
+		labels[n] = labelValues[i]
+	}
+
+	samples, _ := b.rules.Apply(transform.Sample{
+		Collector: "enhanced",
+		Name:      name,
+		Labels:    labels,
+		Value:     value,
+		Timestamp: b.timestamp,
+		BootTime:  b.bootTime,
+	})
+
+	res := make([]prometheus.Metric, 0, len(samples))
+	for _, s := range samples {
+		t := valueType
+		if s.Counter {
+			t = prometheus.CounterValue
+		}
+		desc := prometheus.NewDesc(s.Name, help, nil, s.Labels)
+		res = append(res, prometheus.MustNewConstMetric(desc, t, s.Value))
+	}
+	return res
+}
+
+// makeGauge returns Prometheus gauges for given reflect.Value.
+func makeGauge(b *builder, name, help string, labelKeys, labelValues []string, value reflect.Value) []prometheus.Metric {
 	// skip nil fields
 	if value.Kind() == reflect.Ptr {
 		if value.IsNil() {
@@ -192,55 +262,43 @@ func makeGauge(desc *prometheus.Desc, labelValues []string, value reflect.Value)
 	case reflect.Int, reflect.Int64:
 		f = float64(value.Int())
 	default:
-		panic(fmt.Errorf("can't make a metric value for %s from %v (%s)", desc, value, kind))
+		panic(fmt.Errorf("can't make a metric value for %s from %v (%s)", name, value, kind))
 	}
 
-	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, f, labelValues...)
+	return b.metrics(name, help, prometheus.GaugeValue, f, labelKeys, labelValues)
 }
 
 // makeGenericMetrics makes metrics for all structure fields.
-func makeGenericMetrics(s interface{}, namePrefix string, constLabels prometheus.Labels) []prometheus.Metric {
+func makeGenericMetrics(s interface{}, namePrefix string, b *builder) []prometheus.Metric {
 	t := reflect.TypeOf(s)
 	v := reflect.ValueOf(s)
 	res := make([]prometheus.Metric, 0, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		tags := t.Field(i).Tag
 		name, help := tags.Get("json"), tags.Get("help")
-		desc := prometheus.NewDesc(namePrefix+name, help, nil, constLabels)
-		m := makeGauge(desc, nil, v.Field(i))
-		if m != nil {
-			res = append(res, m)
-		}
+		res = append(res, makeGauge(b, namePrefix+name, help, nil, nil, v.Field(i))...)
 	}
 	return res
 }
 
 // makeNodeCPUMetrics returns node_exporter-like node_cpu_average metrics.
-func makeNodeCPUMetrics(s *cpuUtilization, constLabels prometheus.Labels) []prometheus.Metric {
-	// add "cpu" constant label
-	labels := prometheus.Labels{"cpu": "All"}
-	for k, v := range constLabels {
-		labels[k] = v
-	}
+func makeNodeCPUMetrics(s *cpuUtilization, b *builder) []prometheus.Metric {
+	// add "cpu" label, move mode to label
+	labelKeys := []string{"cpu", "mode"}
 
-	// move mode to label
 	t := reflect.TypeOf(*s)
 	v := reflect.ValueOf(*s)
 	res := make([]prometheus.Metric, 0, t.NumField())
-	desc := prometheus.NewDesc("node_cpu_average", "The percentage of CPU utilization.", []string{"mode"}, labels)
 	for i := 0; i < t.NumField(); i++ {
 		tags := t.Field(i).Tag
 		mode := tags.Get("json")
-		m := makeGauge(desc, []string{mode}, v.Field(i))
-		if m != nil {
-			res = append(res, m)
-		}
+		res = append(res, makeGauge(b, "node_cpu_average", "The percentage of CPU utilization.", labelKeys, []string{"All", mode}, v.Field(i))...)
 	}
 	return res
 }
 
 // makeRDSDiskIOMetrics returns rdsosmetrics_diskIO_ metrics.
-func makeRDSDiskIOMetrics(s *diskIO, prefix string, constLabels prometheus.Labels) []prometheus.Metric {
+func makeRDSDiskIOMetrics(s *diskIO, prefix string, b *builder) []prometheus.Metric {
 	// move device name to label
 	labelKeys := []string{"device"}
 	labelValues := []string{s.Device}
@@ -254,38 +312,38 @@ func makeRDSDiskIOMetrics(s *diskIO, prefix string, constLabels prometheus.Label
 		if name == "device" {
 			continue
 		}
-		desc := prometheus.NewDesc(prefix+name, help, labelKeys, constLabels)
-		m := makeGauge(desc, labelValues, v.Field(i))
-		if m != nil {
-			res = append(res, m)
-		}
+		res = append(res, makeGauge(b, prefix+name, help, labelKeys, labelValues, v.Field(i))...)
 	}
 	return res
 }
 
 // makeNodeDiskMetrics returns node_exporter-like node_disk_ metrics.
-func makeNodeDiskMetrics(s *diskIO, constLabels prometheus.Labels) []prometheus.Metric {
+func makeNodeDiskMetrics(s *diskIO, b *builder) []prometheus.Metric {
 	// move device name to label
 	labelKeys := []string{"device"}
 	labelValues := []string{s.Device}
-	res := make([]prometheus.Metric, 0, 2)
+	res := make([]prometheus.Metric, 0, 4)
 
-	if s.ReadKb != nil {
-		desc := prometheus.NewDesc("node_disk_read_bytes_total", "The total number of bytes read successfully.", labelKeys, constLabels)
-		m := prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(*s.ReadKb*1024), labelValues...)
-		res = append(res, m)
+	// rates are converted to bytes and counters by transform.Defaults;
+	// readKb and writeKb are not totals since instance start, so they are not used
+	if s.ReadKbPS != nil {
+		res = append(res, b.metrics("node_disk_read_bytes_total", "The total number of bytes read successfully.",
+			prometheus.GaugeValue, *s.ReadKbPS, labelKeys, labelValues)...)
 	}
-	if s.WriteKb != nil {
-		desc := prometheus.NewDesc("node_disk_written_bytes_total", "The total number of bytes written successfully.", labelKeys, constLabels)
-		m := prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(*s.WriteKb*1024), labelValues...)
-		res = append(res, m)
+	if s.WriteKbPS != nil {
+		res = append(res, b.metrics("node_disk_written_bytes_total", "The total number of bytes written successfully.",
+			prometheus.GaugeValue, *s.WriteKbPS, labelKeys, labelValues)...)
 	}
+	res = append(res, b.metrics("node_disk_reads_completed_total", "The total number of reads completed successfully.",
+		prometheus.GaugeValue, s.ReadIOsPS, labelKeys, labelValues)...)
+	res = append(res, b.metrics("node_disk_writes_completed_total", "The total number of writes completed successfully.",
+		prometheus.GaugeValue, s.WriteIOsPS, labelKeys, labelValues)...)
 
 	return res
 }
 
 // makeRDSFileSysMetrics returns rdsosmetrics_fileSys_ metrics.
-func makeRDSFileSysMetrics(s *fileSys, constLabels prometheus.Labels) []prometheus.Metric {
+func makeRDSFileSysMetrics(s *fileSys, b *builder) []prometheus.Metric {
 	// move name and mount point to labels
 	labelKeys := []string{"name", "mount_point"}
 	labelValues := []string{s.Name, s.MountPoint}
@@ -300,68 +358,55 @@ func makeRDSFileSysMetrics(s *fileSys, constLabels prometheus.Labels) []promethe
 		case "name", "mountPoint":
 			continue
 		}
-		desc := prometheus.NewDesc("rdsosmetrics_fileSys_"+name, help, labelKeys, constLabels)
-		m := makeGauge(desc, labelValues, v.Field(i))
-		if m != nil {
-			res = append(res, m)
-		}
+		res = append(res, makeGauge(b, "rdsosmetrics_fileSys_"+name, help, labelKeys, labelValues, v.Field(i))...)
 	}
 	return res
 }
 
 // makeNodeFilesystemMetrics returns node_exporter-like node_filesystem_ metrics.
-func makeNodeFilesystemMetrics(s *fileSys, constLabels prometheus.Labels) []prometheus.Metric {
+func makeNodeFilesystemMetrics(s *fileSys, b *builder) []prometheus.Metric {
 	// all labels are used by our dashboards, so fill both device and fstype with the same value
 	labelKeys := []string{"device", "fstype", "mountpoint"}
 	labelValues := []string{s.Name, s.Name, s.MountPoint}
 	res := make([]prometheus.Metric, 0, 5)
 
-	desc := prometheus.NewDesc("node_filesystem_files", "Filesystem total file nodes.", labelKeys, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(s.MaxFiles*1024), labelValues...))
-	desc = prometheus.NewDesc("node_filesystem_files_free", "Filesystem total free file nodes.", labelKeys, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64((s.MaxFiles-s.UsedFiles)*1024), labelValues...))
+	res = append(res, b.metrics("node_filesystem_files", "Filesystem total file nodes.",
+		prometheus.GaugeValue, float64(s.MaxFiles), labelKeys, labelValues)...)
+	res = append(res, b.metrics("node_filesystem_files_free", "Filesystem total free file nodes.",
+		prometheus.GaugeValue, float64(s.MaxFiles-s.UsedFiles), labelKeys, labelValues)...)
 
 	// report the same value for node_filesystem_free and node_filesystem_avail because we use both metrics in our dashboards
-	desc = prometheus.NewDesc("node_filesystem_size_bytes", "Filesystem size in bytes.", labelKeys, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(s.Total*1024), labelValues...))
-	desc = prometheus.NewDesc("node_filesystem_free_bytes", "Filesystem free space in bytes.", labelKeys, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64((s.Total-s.Used)*1024), labelValues...))
-	desc = prometheus.NewDesc("node_filesystem_avail_bytes", "Filesystem space available to non-root users in bytes.", labelKeys, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64((s.Total-s.Used)*1024), labelValues...))
+	res = append(res, b.metrics("node_filesystem_size_bytes", "Filesystem size in bytes.",
+		prometheus.GaugeValue, float64(s.Total), labelKeys, labelValues)...)
+	res = append(res, b.metrics("node_filesystem_free_bytes", "Filesystem free space in bytes.",
+		prometheus.GaugeValue, float64(s.Total-s.Used), labelKeys, labelValues)...)
+	res = append(res, b.metrics("node_filesystem_avail_bytes", "Filesystem space available to non-root users in bytes.",
+		prometheus.GaugeValue, float64(s.Total-s.Used), labelKeys, labelValues)...)
 
 	return res
 }
 
 // makeNodeLoadMetrics returns node_exporter-like node_load1 metric.
-func makeNodeLoadMetrics(s *loadAverageMinute, constLabels prometheus.Labels) []prometheus.Metric {
-	desc := prometheus.NewDesc("node_load1", "The number of processes requesting CPU time over the last minute.", nil, constLabels)
-	m := prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, s.One)
-	return []prometheus.Metric{m}
+func makeNodeLoadMetrics(s *loadAverageMinute, b *builder) []prometheus.Metric {
+	return b.metrics("node_load1", "The number of processes requesting CPU time over the last minute.",
+		prometheus.GaugeValue, s.One, nil, nil)
 }
 
 // makeNodeMemoryMetrics returns node_exporter-like node_memory_ metrics.
-func makeNodeMemoryMetrics(s *memory, constLabels prometheus.Labels) []prometheus.Metric {
+func makeNodeMemoryMetrics(s *memory, b *builder) []prometheus.Metric {
 	t := reflect.TypeOf(*s)
 	v := reflect.ValueOf(*s)
 	res := make([]prometheus.Metric, 0, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		tags := t.Field(i).Tag
-		suffix, multiplierS := tags.Get("node"), tags.Get("m")
-		multiplier, err := strconv.ParseInt(multiplierS, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		desc := prometheus.NewDesc("node_memory_"+suffix, "Memory information field "+suffix+".", nil, constLabels)
-		m := makeGauge(desc, nil, reflect.ValueOf(v.Field(i).Int()*multiplier))
-		if m != nil {
-			res = append(res, m)
-		}
+		suffix := tags.Get("node")
+		res = append(res, makeGauge(b, "node_memory_"+suffix, "Memory information field "+suffix+".", nil, nil, v.Field(i))...)
 	}
 	return res
 }
 
 // makeRDSNetworkMetrics returns rdsosmetrics_network_ metrics.
-func makeRDSNetworkMetrics(s *network, constLabels prometheus.Labels) []prometheus.Metric {
+func makeRDSNetworkMetrics(s *network, b *builder) []prometheus.Metric {
 	// move interface name to label
 	labelKeys := []string{"interface"}
 	labelValues := []string{s.Interface}
@@ -375,17 +420,29 @@ func makeRDSNetworkMetrics(s *network, constLabels prometheus.Labels) []promethe
 		if name == "interface" {
 			continue
 		}
-		desc := prometheus.NewDesc("rdsosmetrics_network_"+name, help, labelKeys, constLabels)
-		m := makeGauge(desc, labelValues, v.Field(i))
-		if m != nil {
-			res = append(res, m)
-		}
+		res = append(res, makeGauge(b, "rdsosmetrics_network_"+name, help, labelKeys, labelValues, v.Field(i))...)
 	}
 	return res
 }
 
+// makeNodeNetworkMetrics returns node_exporter-like node_network_ metrics.
+func makeNodeNetworkMetrics(s *network, b *builder) []prometheus.Metric {
+	// move interface name to label
+	labelKeys := []string{"device"}
+	labelValues := []string{s.Interface}
+	res := make([]prometheus.Metric, 0, 2)
+
+	// rates are converted to counters by transform.Defaults
+	res = append(res, b.metrics("node_network_receive_bytes_total", "Network device statistic receive_bytes.",
+		prometheus.GaugeValue, s.Rx, labelKeys, labelValues)...)
+	res = append(res, b.metrics("node_network_transmit_bytes_total", "Network device statistic transmit_bytes.",
+		prometheus.GaugeValue, s.Tx, labelKeys, labelValues)...)
+
+	return res
+}
+
 // makeRDSProcessListMetrics returns rdsosmetrics_processList_ metrics.
-func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels) []prometheus.Metric {
+func makeRDSProcessListMetrics(s *processList, b *builder) []prometheus.Metric {
 	// move process name, ID, parent ID, thread ID to labels
 	labelKeys := []string{"name", "id", "parentID", "tgid"}
 	labelValues := []string{s.Name, strconv.Itoa(s.ID), strconv.Itoa(s.ParentID), strconv.Itoa(s.TGID)}
@@ -404,48 +461,37 @@ func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels) []
 		case "name", "id", "parentID", "tgid":
 			continue
 		}
-		desc := prometheus.NewDesc("rdsosmetrics_processList_"+name, help, labelKeys, constLabels)
-		m := makeGauge(desc, labelValues, v.Field(i))
-		if m != nil {
-			res = append(res, m)
-		}
+		res = append(res, makeGauge(b, "rdsosmetrics_processList_"+name, help, labelKeys, labelValues, v.Field(i))...)
 	}
 	return res
 }
 
 // makeNodeMemorySwapMetrics returns node_exporter-like node_memory_ metrics for swap.
-func makeNodeMemorySwapMetrics(s *swap, constLabels prometheus.Labels) []prometheus.Metric {
+func makeNodeMemorySwapMetrics(s *swap, b *builder) []prometheus.Metric {
 	t := reflect.TypeOf(*s)
 	v := reflect.ValueOf(*s)
 	res := make([]prometheus.Metric, 0, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		tags := t.Field(i).Tag
-		name, multiplierS, help := tags.Get("node"), tags.Get("m"), tags.Get("nodehelp")
-		multiplier, err := strconv.ParseFloat(multiplierS, 64)
-		if err != nil {
-			panic(err)
-		}
-		desc := prometheus.NewDesc(name, help, nil, constLabels)
-		m := makeGauge(desc, nil, reflect.ValueOf(v.Field(i).Float()*multiplier))
-		if m != nil {
-			res = append(res, m)
-		}
+		name, help := tags.Get("node"), tags.Get("nodehelp")
+		res = append(res, makeGauge(b, name, help, nil, nil, v.Field(i))...)
 	}
 	return res
 }
 
 // makeNodeProcsMetrics returns node_exporter-like node_procs_ metrics.
-func makeNodeProcsMetrics(s *tasks, constLabels prometheus.Labels) []prometheus.Metric {
+func makeNodeProcsMetrics(s *tasks, b *builder) []prometheus.Metric {
 	res := make([]prometheus.Metric, 0, 2)
-	desc := prometheus.NewDesc("node_procs_blocked", "Number of processes blocked waiting for I/O to complete.", nil, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(s.Blocked)))
-	desc = prometheus.NewDesc("node_procs_running", "Number of processes in runnable state.", nil, constLabels)
-	res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(s.Running)))
+	res = append(res, b.metrics("node_procs_blocked", "Number of processes blocked waiting for I/O to complete.",
+		prometheus.GaugeValue, float64(s.Blocked), nil, nil)...)
+	res = append(res, b.metrics("node_procs_running", "Number of processes in runnable state.",
+		prometheus.GaugeValue, float64(s.Running), nil, nil)...)
 	return res
 }
 
-// makePrometheusMetrics returns all Prometheus metrics for given osMetrics.
-func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]string) []prometheus.Metric {
+// makePrometheusMetrics returns all Prometheus metrics for given osMetrics with given transformation rules applied.
+// Rates are integrated into counters by rules, so it should be called once for every sample, in order.
+func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]string, rules *transform.Rules) []prometheus.Metric {
 	res := make([]prometheus.Metric, 0, 100)
 
 	constLabels := prometheus.Labels{
@@ -459,79 +505,78 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 			constLabels[n] = v
 		}
 	}
+	b := &builder{
+		constLabels: constLabels,
+		rules:       rules,
+		timestamp:   m.Timestamp,
+		bootTime:    m.bootTime(),
+	}
 
-	res = append(res, prometheus.MustNewConstMetric(
-		prometheus.NewDesc("rdsosmetrics_timestamp", "Metrics timestamp (UNIX seconds).", nil, constLabels),
-		prometheus.CounterValue,
-		float64(m.Timestamp.Unix()),
-	))
-
-	// TODO Parse uptime: https://jira.percona.com/browse/PMM-2131
+	res = append(res, b.metrics("rdsosmetrics_timestamp", "Metrics timestamp (UNIX seconds).",
+		prometheus.CounterValue, float64(m.Timestamp.Unix()), nil, nil)...)
 
-	res = append(res, prometheus.MustNewConstMetric(
-		prometheus.NewDesc("rdsosmetrics_General_numVCPUs", "The number of virtual CPUs for the DB instance.", nil, constLabels),
-		prometheus.GaugeValue,
-		float64(m.NumVCPUs)),
-	)
+	res = append(res, b.metrics("rdsosmetrics_General_numVCPUs", "The number of virtual CPUs for the DB instance.",
+		prometheus.GaugeValue, float64(m.NumVCPUs), nil, nil)...)
 
 	// always make both generic and node_exporter-like metrics
 
-	metrics := makeGenericMetrics(m.CPUUtilization, "rdsosmetrics_cpuUtilization_", constLabels)
+	metrics := makeGenericMetrics(m.CPUUtilization, "rdsosmetrics_cpuUtilization_", b)
 	res = append(res, metrics...)
-	// metrics = makeNodeCPUMetrics(&m.CPUUtilization, constLabels)
+	// metrics = makeNodeCPUMetrics(&m.CPUUtilization, b)
 	// res = append(res, metrics...)
 
 	for _, disk := range m.DiskIO {
-		metrics = makeRDSDiskIOMetrics(&disk, "rdsosmetrics_diskIO_", constLabels)
+		metrics = makeRDSDiskIOMetrics(&disk, "rdsosmetrics_diskIO_", b)
+		res = append(res, metrics...)
+		metrics = makeNodeDiskMetrics(&disk, b)
 		res = append(res, metrics...)
-		// metrics = makeNodeDiskMetrics(&disk, constLabels)
-		// res = append(res, metrics...)
 	}
 
 	for _, phyDevice := range m.PhysicalDeviceIO {
-		metrics = makeRDSDiskIOMetrics(&phyDevice, "rdsosmetrics_physicalDeviceIO_", constLabels)
+		metrics = makeRDSDiskIOMetrics(&phyDevice, "rdsosmetrics_physicalDeviceIO_", b)
 		res = append(res, metrics...)
-		// metrics = makeNodeDiskMetrics(&disk, constLabels)
+		// metrics = makeNodeDiskMetrics(&disk, b)
 		// res = append(res, metrics...)
 	}
 
 	for _, fs := range m.FileSys {
-		metrics = makeRDSFileSysMetrics(&fs, constLabels)
+		metrics = makeRDSFileSysMetrics(&fs, b)
 		res = append(res, metrics...)
-		// metrics = makeNodeFilesystemMetrics(&fs, constLabels)
+		// metrics = makeNodeFilesystemMetrics(&fs, b)
 		// res = append(res, metrics...)
 	}
 
-	metrics = makeGenericMetrics(m.LoadAverageMinute, "rdsosmetrics_loadAverageMinute_", constLabels)
+	metrics = makeGenericMetrics(m.LoadAverageMinute, "rdsosmetrics_loadAverageMinute_", b)
 	res = append(res, metrics...)
-	// metrics = makeNodeLoadMetrics(&m.LoadAverageMinute, constLabels)
+	// metrics = makeNodeLoadMetrics(&m.LoadAverageMinute, b)
 	// res = append(res, metrics...)
 
-	metrics = makeGenericMetrics(m.Memory, "rdsosmetrics_memory_", constLabels)
+	metrics = makeGenericMetrics(m.Memory, "rdsosmetrics_memory_", b)
 	res = append(res, metrics...)
-	// metrics = makeNodeMemoryMetrics(&m.Memory, constLabels)
+	// metrics = makeNodeMemoryMetrics(&m.Memory, b)
 	// res = append(res, metrics...)
 
 	for _, n := range m.Network {
-		metrics = makeRDSNetworkMetrics(&n, constLabels)
+		metrics = makeRDSNetworkMetrics(&n, b)
+		res = append(res, metrics...)
+		metrics = makeNodeNetworkMetrics(&n, b)
 		res = append(res, metrics...)
-		// we can't make node_exporter-like metrics: AWS gives us rates, node_exporter - total counters
 	}
 
 	// for _, p := range m.ProcessList {
-	// 	metrics = makeRDSProcessListMetrics(&p, constLabels)
+	// 	metrics = makeRDSProcessListMetrics(&p, b)
 	// 	res = append(res, metrics...)
 	// 	// no node_exporter-like metrics
 	// }
 
-	metrics = makeGenericMetrics(m.Swap, "rdsosmetrics_swap_", constLabels)
+	metrics = makeGenericMetrics(m.Swap, "rdsosmetrics_swap_", b)
 	res = append(res, metrics...)
-	// metrics = makeNodeMemorySwapMetrics(&m.Swap, constLabels)
+	// metrics = makeNodeMemorySwapMetrics(&m.Swap, b)
 	// res = append(res, metrics...)
 
-	metrics = makeGenericMetrics(m.Tasks, "rdsosmetrics_tasks_", constLabels)
+	metrics = makeGenericMetrics(m.Tasks, "rdsosmetrics_tasks_", b)
 	res = append(res, metrics...)
-	// metrics = makeNodeProcsMetrics(&m.Tasks, constLabels)
+	// metrics = makeNodeProcsMetrics(&m.Tasks, b)
 	// res = append(res, metrics...)
 
 	return res
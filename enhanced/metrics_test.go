@@ -3,10 +3,15 @@ package enhanced
 import (
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
 func TestParse(t *testing.T) {
@@ -26,7 +31,7 @@ func TestParse(t *testing.T) {
 			m, err := parseOSMetrics(readTestDataJSON(t, data.instance), true)
 			require.NoError(t, err)
 
-			actualMetrics := helpers.ReadMetrics(m.makePrometheusMetrics(data.region, nil))
+			actualMetrics := helpers.ReadMetrics(m.makePrometheusMetrics(data.region, nil, transform.New(nil)))
 			sort.Slice(actualMetrics, func(i, j int) bool { return actualMetrics[i].Less(actualMetrics[j]) })
 			actualLines := helpers.Format(helpers.WriteMetrics(actualMetrics))
 
@@ -46,8 +51,125 @@ func TestParse(t *testing.T) {
 }
 
 func TestParseUptime(t *testing.T) {
-	t.Skip("TODO Parse uptime https://jira.percona.com/browse/PMM-2131")
+	for s, expected := range map[string]time.Duration{
+		"01:45:58":           time.Hour + 45*time.Minute + 58*time.Second,
+		"1 day, 07:11:58":    31*time.Hour + 11*time.Minute + 58*time.Second,
+		"332 days, 01:07:34": 332*24*time.Hour + time.Hour + 7*time.Minute + 34*time.Second,
+	} {
+		actual, err := parseUptime(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, actual, s)
+	}
+
+	for _, s := range []string{"", "1 week, 01:45:58", "01:45"} {
+		_, err := parseUptime(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDefaultTransforms(t *testing.T) {
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := &builder{
+		constLabels: prometheus.Labels{"region": "us-east-1", "instance_identifier": "db1"},
+		rules:       transform.New(nil),
+		timestamp:   ts,
+	}
+
+	// values returns metric name (and device label, if present) => value and type
+	values := func(metrics []prometheus.Metric) map[string]*helpers.Metric {
+		res := make(map[string]*helpers.Metric)
+		for _, m := range helpers.ReadMetrics(metrics) {
+			key := m.Name
+			if device := m.Labels["device"]; device != "" {
+				key += "/" + device
+			}
+			res[key] = m
+		}
+		return res
+	}
+
+	t.Run("Memory", func(t *testing.T) {
+		v := values(makeNodeMemoryMetrics(&memory{Free: 2, Total: 4}, b))
+		assert.Equal(t, 2048.0, v["node_memory_MemFree_bytes"].Value)
+		assert.Equal(t, 4096.0, v["node_memory_MemTotal_bytes"].Value)
+		assert.Equal(t, dto.MetricType_GAUGE, v["node_memory_MemFree_bytes"].Type)
+		assert.Equal(t, 0.0, v["node_memory_HugePages_Free"].Value)
+	})
+
+	t.Run("Swap", func(t *testing.T) {
+		v := values(makeNodeMemorySwapMetrics(&swap{Free: 1, In: 8, Out: 2}, b))
+		assert.Equal(t, 1024.0, v["node_memory_SwapFree_bytes"].Value)
+		assert.Equal(t, 2.0, v["node_vmstat_pswpin"].Value)
+		assert.Equal(t, 0.5, v["node_vmstat_pswpout"].Value)
+	})
+
+	t.Run("Filesystem", func(t *testing.T) {
+		v := values(makeNodeFilesystemMetrics(&fileSys{Name: "rdsfilesys", Total: 10, Used: 4, MaxFiles: 100, UsedFiles: 10}, b))
+		assert.Equal(t, 10240.0, v["node_filesystem_size_bytes/rdsfilesys"].Value)
+		assert.Equal(t, 6144.0, v["node_filesystem_avail_bytes/rdsfilesys"].Value)
+	})
+
+	t.Run("Disk", func(t *testing.T) {
+		readKb, readKbPS, writeKbPS := 1000, 2.0, 0.5
+		d := &diskIO{Device: "rdsdev", ReadKb: &readKb, ReadKbPS: &readKbPS, WriteKbPS: &writeKbPS, ReadIOsPS: 10}
+		v := values(makeNodeDiskMetrics(d, b))
+		assert.Equal(t, 0.0, v["node_disk_read_bytes_total/rdsdev"].Value)
+		assert.Equal(t, dto.MetricType_COUNTER, v["node_disk_read_bytes_total/rdsdev"].Type)
+
+		next := *b
+		next.timestamp = ts.Add(time.Minute)
+		v = values(makeNodeDiskMetrics(d, &next))
+		assert.Equal(t, 2.0*1024*60, v["node_disk_read_bytes_total/rdsdev"].Value)
+		assert.Equal(t, 0.5*1024*60, v["node_disk_written_bytes_total/rdsdev"].Value)
+		assert.Equal(t, 600.0, v["node_disk_reads_completed_total/rdsdev"].Value)
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		n := &network{Interface: "eth0", Rx: 100, Tx: 10}
+		v := values(makeNodeNetworkMetrics(n, b))
+		assert.Equal(t, 0.0, v["node_network_receive_bytes_total/eth0"].Value)
+		assert.Equal(t, dto.MetricType_COUNTER, v["node_network_receive_bytes_total/eth0"].Type)
+
+		next := *b
+		next.timestamp = ts.Add(time.Minute)
+		v = values(makeNodeNetworkMetrics(n, &next))
+		assert.Equal(t, 6000.0, v["node_network_receive_bytes_total/eth0"].Value)
+		assert.Equal(t, 600.0, v["node_network_transmit_bytes_total/eth0"].Value)
+
+		// generic metrics are not changed
+		v = values(makeRDSNetworkMetrics(n, &next))
+		assert.Equal(t, 100.0, v["rdsosmetrics_network_rx"].Value)
+		assert.Equal(t, dto.MetricType_GAUGE, v["rdsosmetrics_network_rx"].Type)
+	})
+}
+
+func TestRateCounters(t *testing.T) {
+	m, err := parseOSMetrics(readTestDataJSON(t, "mysql-57"), true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 12, 12, 10, 29, 20, 0, time.UTC), m.bootTime())
+
+	rules := transform.New(nil)
+	counters := func() map[string]float64 {
+		res := make(map[string]float64)
+		for _, metric := range helpers.ReadMetrics(m.makePrometheusMetrics("us-west-2", nil, rules)) {
+			if metric.Name == "node_network_receive_bytes_total" {
+				assert.Equal(t, dto.MetricType_COUNTER, metric.Type)
+				res[metric.Labels["device"]] = metric.Value
+			}
+		}
+		return res
+	}
+
+	assert.Equal(t, map[string]float64{"eth0": 0}, counters())
+
+	// the next sample
+	rx := m.Network[0].Rx
+	m.Timestamp = m.Timestamp.Add(time.Minute)
+	m.Uptime = "360 days, 00:05:40"
+	assert.Equal(t, map[string]float64{"eth0": rx * 60}, counters())
 
-	_ = "01:45:58"
-	_ = "1 day, 07:11:58"
+	// restart
+	m.Timestamp = m.Timestamp.Add(time.Minute)
+	m.Uptime = "00:00:30"
+	assert.Equal(t, map[string]float64{"eth0": 0}, counters())
 }
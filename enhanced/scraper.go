@@ -12,21 +12,38 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/duyhai-bic/rds_exporter/client"
 	"github.com/duyhai-bic/rds_exporter/sessions"
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
+// ScrapeDuration tracks durations of enhanced metrics scrape cycles for all sessions.
+var ScrapeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:                        "rds_exporter_enhanced_scrape_duration_seconds",
+	Help:                        "Enhanced metrics scrape cycle duration, in seconds.",
+	Buckets:                     prometheus.ExponentialBuckets(0.1, 2, 10),
+	NativeHistogramBucketFactor: 1.1,
+})
+
+// Scrapes counts enhanced metrics scrape cycles by result: success, error, or circuit_open.
+var Scrapes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "rds_exporter_enhanced_scrapes_total",
+	Help: "Total number of enhanced metrics scrape cycles by result.",
+}, []string{"result"})
+
 // scraper retrieves metrics from several RDS instances sharing a single session.
 type scraper struct {
 	instances      []sessions.Instance
 	logStreamNames []string
 	svc            *cloudwatchlogs.CloudWatchLogs
 	nextStartTime  time.Time
+	transforms     *transform.Rules // nil if not set
 	logger         log.Logger
 
 	testDisallowUnknownFields bool // for tests only
 }
 
-func newScraper(session *session.Session, instances []sessions.Instance, logger log.Logger) *scraper {
+func newScraper(session *session.Session, instances []sessions.Instance, transforms *transform.Rules, logger log.Logger) *scraper {
 	logStreamNames := make([]string, 0, len(instances))
 	for _, instance := range instances {
 		logStreamNames = append(logStreamNames, instance.ResourceID)
@@ -37,6 +54,7 @@ func newScraper(session *session.Session, instances []sessions.Instance, logger
 		logStreamNames: logStreamNames,
 		svc:            cloudwatchlogs.New(session),
 		nextStartTime:  time.Now().Add(-3 * time.Minute).Round(0), // strip monotonic clock reading
+		transforms:     transforms,
 		logger:         log.With(logger, "component", "enhanced"),
 	}
 }
@@ -73,6 +91,14 @@ func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- m
 
 // scrape performs a single scrape.
 func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, map[string]string) {
+	start := time.Now()
+	result := "success"
+	defer func() {
+		ScrapeDuration.Observe(time.Since(start).Seconds())
+		Scrapes.WithLabelValues(result).Inc()
+	}()
+
+	ctx = client.WithCollector(ctx, "enhanced")
 
 	allMetrics := make(map[string]map[time.Time][]prometheus.Metric) // ResourceID -> event timestamp -> metrics
 	allMessages := make(map[string]map[time.Time]string)             // ResourceID -> event timestamp -> message
@@ -141,7 +167,7 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 				if allMetrics[instance.ResourceID] == nil {
 					allMetrics[instance.ResourceID] = make(map[time.Time][]prometheus.Metric)
 				}
-				allMetrics[instance.ResourceID][timestamp] = osMetrics.makePrometheusMetrics(instance.Region, instance.Labels)
+				allMetrics[instance.ResourceID][timestamp] = osMetrics.makePrometheusMetrics(instance.Region, instance.MetricLabels(), s.transforms)
 
 				if allMessages[instance.ResourceID] == nil {
 					allMessages[instance.ResourceID] = make(map[time.Time]string)
@@ -152,7 +178,16 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 			return true // continue pagination
 		}
 		if err := s.svc.FilterLogEventsPagesWithContext(ctx, input, collectAllMetrics); err != nil {
+			if client.IsCircuitOpen(err) {
+				// collector keeps serving the last metrics
+				level.Debug(s.logger).Log("msg", "Circuit breaker is open, skipping scrape.", "error", err)
+				if result == "success" {
+					result = "circuit_open"
+				}
+				continue
+			}
 			level.Error(s.logger).Log("msg", "Failed to filter log events.", "error", err)
+			result = "error"
 		}
 	}
 	// get better times
@@ -9,16 +9,20 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
 	"github.com/prometheus/common/version"
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/duyhai-bic/rds_exporter/basic"
 	"github.com/duyhai-bic/rds_exporter/client"
 	"github.com/duyhai-bic/rds_exporter/config"
 	"github.com/duyhai-bic/rds_exporter/enhanced"
+	"github.com/duyhai-bic/rds_exporter/proxy"
 	"github.com/duyhai-bic/rds_exporter/sessions"
+	"github.com/duyhai-bic/rds_exporter/transform"
 )
 
 //nolint:lll
@@ -26,19 +30,95 @@ var (
 	listenAddressF       = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9042").String()
 	basicMetricsPathF    = kingpin.Flag("web.basic-telemetry-path", "Path under which to expose exporter's basic metrics.").Default("/basic").String()
 	enhancedMetricsPathF = kingpin.Flag("web.enhanced-telemetry-path", "Path under which to expose exporter's enhanced metrics.").Default("/enhanced").String()
+	proxyMetricsPathF    = kingpin.Flag("web.proxy-telemetry-path", "Path under which to expose exporter's RDS Proxy metrics.").Default("/proxy").String()
+	basicCollectorF      = kingpin.Flag("collector.basic", "Enable basic metrics collector that uses CloudWatch GetMetricData API.").Default("false").Bool()
+	proxyCollectorF      = kingpin.Flag("collector.proxy", "Enable RDS Proxy metrics collector that uses RDS and CloudWatch APIs.").Default("false").Bool()
+	telemetryPathF       = kingpin.Flag("web.telemetry-path", "Path under which to expose exporter's own metrics.").Default("/metrics").String()
 	configFileF          = kingpin.Flag("config.file", "Path to configuration file.").Default("config.yml").String()
-	logTraceF            = kingpin.Flag("log.trace", "Enable verbose tracing of AWS requests (will log credentials).").Default("false").Bool()
-	logger               = log.NewNopLogger()
+	logTraceF            = kingpin.Flag("log.trace", "Enable tracing of AWS requests with credentials redacted; requires debug log level.").Default("false").Bool()
+	logTraceInstanceF    = kingpin.Flag("log.trace.instance", "Trace only AWS requests of session used by given instance.").String()
+	logTraceOperationF   = kingpin.Flag("log.trace.operation", "Trace only given AWS API operation, e.g. FilterLogEvents.").String()
+
+	legacySummaryF           = kingpin.Flag("aws.request-duration.legacy-summary", "Expose AWS API latency as rds_exporter_responses_durations_seconds summary instead of histogram.").Default("false").Bool()
+	latencyBucketsF          = kingpin.Flag("aws.request-duration.bucket", "AWS API latency histogram bucket upper bound, in seconds; may be repeated.").Float64List()
+	nativeFactorF            = kingpin.Flag("aws.request-duration.native-histogram-bucket-factor", "Enable AWS API latency native histogram with given bucket growth factor (should be greater than 1).").Default("0").Float64()
+	httpTimeoutF             = kingpin.Flag("aws.http.timeout", "AWS API request attempt timeout (overrides http_client.timeout).").Duration()
+	httpServiceTimeoutsF     = kingpin.Flag("aws.http.service-timeout", "AWS API request attempt timeout for given service, e.g. logs=1m; may be repeated.").StringMap()
+	httpProxyURLF            = kingpin.Flag("aws.http.proxy-url", "Proxy URL for AWS API requests; HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used if not set.").String()
+	httpNoProxyF             = kingpin.Flag("aws.http.no-proxy", "Host, domain, IP address or CIDR that is accessed without proxy; may be repeated.").Strings()
+	httpCABundleF            = kingpin.Flag("aws.http.ca-bundle", "PEM file with additional trusted CA certificates; AWS_CA_BUNDLE is used if not set.").String()
+	httpMaxIdleConnsF        = kingpin.Flag("aws.http.max-idle-conns", "Maximum number of idle connections to all AWS APIs.").Int()
+	httpMaxIdleConnsPerHostF = kingpin.Flag("aws.http.max-idle-conns-per-host", "Maximum number of idle connections per AWS API endpoint.").Int()
+	httpMaxConnsPerHostF     = kingpin.Flag("aws.http.max-conns-per-host", "Maximum number of connections per AWS API endpoint.").Int()
+	httpIdleConnTimeoutF     = kingpin.Flag("aws.http.idle-conn-timeout", "Idle connections timeout.").Duration()
+
+	priceTableF = kingpin.Flag("aws.price-table", "Path to AWS API price table file for cost accounting; built-in us-east-1 prices are used if not set.").String()
+
+	logger = log.NewNopLogger()
 )
 
-func initSession(configFileF *string, client *client.Client, logger log.Logger, logTraceF *bool) (*config.Config, *sessions.Sessions) {
+func loadConfig(configFileF *string, logger log.Logger) *config.Config {
 	cfg, err := config.Load(*configFileF)
 	if err != nil {
 		level.Error(logger).Log("msg", "Can't read configuration file", "error", err)
 		os.Exit(1)
 	}
+	return cfg
+}
 
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, *logTraceF)
+// httpClientConfig returns HTTP client settings from configuration file overridden by flags.
+func httpClientConfig(cfg config.HTTPClient) (config.HTTPClient, error) {
+	if *httpTimeoutF != 0 {
+		cfg.Timeout = *httpTimeoutF
+	}
+	if len(*httpServiceTimeoutsF) != 0 {
+		timeouts := make(map[string]time.Duration, len(cfg.ServiceTimeouts)+len(*httpServiceTimeoutsF))
+		for service, timeout := range cfg.ServiceTimeouts {
+			timeouts[service] = timeout
+		}
+		for service, s := range *httpServiceTimeoutsF {
+			timeout, err := time.ParseDuration(s)
+			if err != nil || timeout <= 0 {
+				return cfg, fmt.Errorf("invalid --aws.http.service-timeout %s=%s", service, s)
+			}
+			timeouts[service] = timeout
+		}
+		cfg.ServiceTimeouts = timeouts
+	}
+	if *httpProxyURLF != "" {
+		cfg.ProxyURL = *httpProxyURLF
+	}
+	if len(*httpNoProxyF) != 0 {
+		cfg.NoProxy = *httpNoProxyF
+	}
+	if *httpCABundleF != "" {
+		cfg.CABundle = *httpCABundleF
+	}
+	if *httpMaxIdleConnsF != 0 {
+		cfg.MaxIdleConns = *httpMaxIdleConnsF
+	}
+	if *httpMaxIdleConnsPerHostF != 0 {
+		cfg.MaxIdleConnsPerHost = *httpMaxIdleConnsPerHostF
+	}
+	if *httpMaxConnsPerHostF != 0 {
+		cfg.MaxConnsPerHost = *httpMaxConnsPerHostF
+	}
+	if *httpIdleConnTimeoutF != 0 {
+		cfg.IdleConnTimeout = *httpIdleConnTimeoutF
+	}
+
+	// AWS SDK can't load CA bundle into our transport, so we load it ourselves
+	if cfg.CABundle == "" {
+		cfg.CABundle = os.Getenv("AWS_CA_BUNDLE")
+	}
+
+	return cfg, nil
+}
+
+func initSession(configFileF *string, client *client.Client, logger log.Logger, trace sessions.TraceOptions) (*config.Config, *sessions.Sessions) {
+	cfg := loadConfig(configFileF, logger)
+
+	sess, err := sessions.New(cfg.Instances, client, logger, trace)
 	if err != nil {
 		level.Error(logger).Log("msg", "Can't create sessions", "error", err)
 		os.Exit(1)
@@ -56,24 +136,90 @@ func main() {
 	level.Info(logger).Log("msg", fmt.Sprintf("Starting RDS exporter %s", version.Info()))
 	level.Info(logger).Log("msg", fmt.Sprintf("Build context %s", version.BuildContext()))
 
-	client := client.New(logger)
+	// client options are read only once on startup
+	cfg := loadConfig(configFileF, logger)
+	var prices []config.Price
+	var err error
+	if *priceTableF != "" {
+		if prices, err = config.LoadPrices(*priceTableF); err != nil {
+			level.Error(logger).Log("msg", "Can't read price table file", "error", err)
+			os.Exit(1)
+		}
+	}
+	httpClient, err := httpClientConfig(cfg.HTTPClient)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid HTTP client settings", "error", err)
+		os.Exit(1)
+	}
+	client, err := client.New(logger, client.Options{
+		LegacySummary:               *legacySummaryF,
+		LatencyBuckets:              *latencyBucketsF,
+		NativeHistogramBucketFactor: *nativeFactorF,
+		RateLimits:                  cfg.RateLimits,
+		Retry:                       cfg.Retry,
+		CircuitBreaker:              cfg.CircuitBreaker,
+		HTTPClient:                  httpClient,
+		Prices:                      prices,
+	})
+	if err != nil {
+		level.Error(logger).Log("msg", "Can't create AWS API client", "error", err)
+		os.Exit(1)
+	}
+
+	trace := sessions.TraceOptions{
+		Enabled:   *logTraceF,
+		Instance:  *logTraceInstanceF,
+		Operation: *logTraceOperationF,
+	}
+	cfg, sess := initSession(configFileF, client, logger, trace)
 
-	_, sess := initSession(configFileF, client, logger, logTraceF)
+	// exporter own metrics: client, Go runtime, process, scrape loops, sessions inventory, and build info
+	inventory := sessions.NewInventory(sess)
+	{
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(client)
+		registry.MustRegister(collectors.NewGoCollector())
+		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		registry.MustRegister(version.NewCollector("rds_exporter"))
+		registry.MustRegister(enhanced.ScrapeDuration, enhanced.Scrapes)
+		if *basicCollectorF {
+			registry.MustRegister(basic.ScrapeDuration, basic.EmptyResults, basic.QueueDepth, basic.InFlight)
+		}
+		if *proxyCollectorF {
+			registry.MustRegister(proxy.ScrapeDuration)
+		}
+		registry.MustRegister(inventory)
+		http.Handle(*telemetryPathF, promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+	}
 
-	// Disable cloudwatch metrics, as we will use YACE for all CW metrics
-	// basic metrics + client metrics + exporter own metrics (ProcessCollector and GoCollector)
-	// basicCollector := basic.New(cfg, sess, logger)
-	// {
-	// 	prometheus.MustRegister(basicCollector)
-	// 	prometheus.MustRegister(client)
-	// 	http.Handle(*basicMetricsPathF, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
-	// 		//ErrorLog:      log.NewErrorLogger(), TODO TS
-	// 		ErrorHandling: promhttp.ContinueOnError,
-	// 	}))
-	// }
+	// basic metrics are disabled by default, as CloudWatch metrics may be collected by other tools
+	var basicCollector *basic.Collector
+	if *basicCollectorF {
+		basicCollector = basic.New(cfg, sess, logger)
+		http.Handle(*basicMetricsPathF, promhttp.HandlerFor(basicCollector.Gatherer(), promhttp.HandlerOpts{
+			//ErrorLog:      log.NewErrorLogger(), TODO TS
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+	}
+
+	// RDS Proxy metrics are disabled by default, as they require additional permissions
+	var proxyCollector *proxy.Collector
+	if *proxyCollectorF {
+		proxyCollector = proxy.New(cfg, sess, logger)
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(proxyCollector)
+		http.Handle(*proxyMetricsPathF, promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+	}
 
 	// enhanced metrics
-	enhancedCollector := enhanced.NewCollector(sess, logger)
+	enhancedCollector := enhanced.NewCollector(sess, transform.New(cfg.Transforms), logger)
+	if basicCollector != nil {
+		enhancedCollector.SetCapacitySource(basicCollector)
+	}
 	{
 		registry := prometheus.NewRegistry()
 		registry.MustRegister(enhancedCollector)
@@ -88,15 +234,26 @@ func main() {
 	go func() {
 		for range ticker.C {
 			level.Info(logger).Log("msg", "Periodic reinitialization of AWS session and configuration")
-			_, sess := initSession(configFileF, client, logger, logTraceF)
-			// Disable cloudwatch metrics, as we will use YACE for all CW metrics
-			// basicCollector.Update(cfg, sess)
+			cfg, sess := initSession(configFileF, client, logger, trace)
+			inventory.Update(sess)
+			if basicCollector != nil {
+				basicCollector.Update(cfg, sess)
+			}
+			if proxyCollector != nil {
+				proxyCollector.Update(cfg, sess)
+			}
 			enhancedCollector.Update(sess, logger)
 		}
 	}()
 
-	// level.Info(logger).Log("msg", fmt.Sprintf("Basic metrics   : http://%s%s", *listenAddressF, *basicMetricsPathF))
+	if *basicCollectorF {
+		level.Info(logger).Log("msg", fmt.Sprintf("Basic metrics   : http://%s%s", *listenAddressF, *basicMetricsPathF))
+	}
+	if *proxyCollectorF {
+		level.Info(logger).Log("msg", fmt.Sprintf("Proxy metrics   : http://%s%s", *listenAddressF, *proxyMetricsPathF))
+	}
 	level.Info(logger).Log("msg", fmt.Sprintf("Enhanced metrics: http://%s%s", *listenAddressF, *enhancedMetricsPathF))
+	level.Info(logger).Log("msg", fmt.Sprintf("Exporter metrics: http://%s%s", *listenAddressF, *telemetryPathF))
 
 	level.Error(logger).Log("error", http.ListenAndServe(*listenAddressF, nil))
 }
@@ -0,0 +1,170 @@
+// Package proxy collects RDS Proxy metrics: proxy information and targets health from RDS API,
+// and proxy metrics from CloudWatch.
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/basic"
+	"github.com/duyhai-bic/rds_exporter/config"
+	"github.com/duyhai-bic/rds_exporter/sessions"
+)
+
+// ScrapeDuration tracks durations of proxy metrics scrapes.
+var ScrapeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:                        "rds_exporter_proxy_scrape_duration_seconds",
+	Help:                        "Proxy metrics scrape cycle duration, in seconds.",
+	Buckets:                     prometheus.ExponentialBuckets(0.1, 2, 10),
+	NativeHistogramBucketFactor: 1.1,
+})
+
+// cacheEntry contains the latest scraped metrics of a single proxy.
+type cacheEntry struct {
+	session string // region/account
+	metrics []prometheus.Metric
+	updated time.Time
+}
+
+// Collector collects RDS Proxy metrics of all sessions in background and serves them from cache.
+// It uses basic collector settings: workers and scrape timeout.
+type Collector struct {
+	rw       sync.RWMutex
+	config   *config.Config
+	sessions *sessions.Sessions
+
+	pool   *basic.Pool
+	cancel context.CancelFunc // stops background refresh
+	l      log.Logger
+
+	cacheRW sync.RWMutex
+	cache   map[string]cacheEntry // region/account/proxy => entry
+}
+
+// New creates a new instance of a Collector, performs the first scrape synchronously,
+// and starts refreshing metrics in background every CloudWatch period.
+func New(cfg *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
+	workers := config.DefaultBasic.Workers
+	if cfg != nil {
+		workers = cfg.Basic.Workers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Collector{
+		config:   cfg,
+		sessions: sessions,
+		pool:     basic.NewPool(ctx, workers),
+		cancel:   cancel,
+		l:        log.With(logger, "component", "proxy"),
+		cache:    make(map[string]cacheEntry),
+	}
+
+	c.refresh(ctx)
+	go basic.RunEveryPeriod(ctx, c.refresh)
+	return c
+}
+
+// Stop stops refreshing metrics in background and pool workers; the last cached metrics are still served.
+func (c *Collector) Stop() {
+	c.cancel()
+}
+
+// Update updates configuration, sessions, and the number of workers.
+func (c *Collector) Update(config *config.Config, sessions *sessions.Sessions) {
+	if config != nil {
+		c.pool.Resize(config.Basic.Workers)
+	}
+
+	c.rw.Lock()
+	c.config = config
+	c.sessions = sessions
+	c.rw.Unlock()
+}
+
+// refresh scrapes proxies of all sessions within scrape timeout and updates cache.
+// Proxies of sessions with errors keep their previous metrics until they are too old.
+func (c *Collector) refresh(ctx context.Context) {
+	start := time.Now()
+
+	c.rw.RLock()
+	sess := c.sessions
+	opts := config.DefaultBasic
+	if c.config != nil {
+		opts = c.config.Basic
+	}
+	c.rw.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.ScrapeTimeout)
+	defer cancel()
+
+	var m sync.Mutex
+	results := make(map[string]cacheEntry) // region/account/proxy => entry without update time
+	failed := make(map[string]struct{})    // region/account of sessions with errors
+
+	var wg sync.WaitGroup
+	for _, s := range newScrapers(sess.AllSessions(), c.pool, c.l) {
+		s := s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := s.scrape(ctx)
+			m.Lock()
+			defer m.Unlock()
+			if err != nil {
+				level.Error(c.l).Log("msg", "Failed to describe proxies.", "region", s.region, "error", err)
+				failed[s.sessionKey()] = struct{}{}
+				return
+			}
+			for key, metrics := range res {
+				results[key] = cacheEntry{session: s.sessionKey(), metrics: metrics}
+			}
+		}()
+	}
+	wg.Wait()
+
+	ScrapeDuration.Observe(time.Since(start).Seconds())
+
+	now := time.Now()
+	c.cacheRW.Lock()
+	defer c.cacheRW.Unlock()
+
+	for key, entry := range c.cache {
+		if _, ok := results[key]; ok {
+			continue
+		}
+		// remove deleted proxies, but keep proxies of failed sessions
+		if _, ok := failed[entry.session]; !ok || now.Sub(entry.updated) > basic.MaxCacheAge {
+			delete(c.cache, key)
+		}
+	}
+	for key, entry := range results {
+		entry.updated = now
+		c.cache[key] = entry
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	// unchecked collector
+}
+
+// Collect implements prometheus.Collector: it sends cached metrics without making any AWS API requests.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.cacheRW.RLock()
+	defer c.cacheRW.RUnlock()
+
+	for _, entry := range c.cache {
+		for _, m := range entry.metrics {
+			ch <- m
+		}
+	}
+}
+
+// check interfaces
+var (
+	_ prometheus.Collector = (*Collector)(nil)
+)
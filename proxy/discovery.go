@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// dbProxy represents a single RDS Proxy with targets of its default target group.
+type dbProxy struct {
+	name         string
+	engineFamily string // MYSQL, POSTGRESQL, or SQLSERVER
+	status       string
+	endpoint     string
+	requireTLS   bool
+	targets      []dbProxyTarget
+}
+
+// dbProxyTarget represents a single RDS instance or Aurora cluster the proxy connects to.
+type dbProxyTarget struct {
+	id     string // instance or cluster identifier
+	typ    string // RDS_INSTANCE, RDS_SERVERLESS_ENDPOINT, or TRACKED_CLUSTER
+	role   string // READ_WRITE, READ_ONLY, or UNKNOWN
+	state  string // AVAILABLE, UNAVAILABLE, or REGISTERING
+	reason string // empty if target is available
+}
+
+// describeProxies returns all proxies with their targets.
+// Proxies with DescribeDBProxyTargets errors are returned without targets.
+func describeProxies(ctx context.Context, session *session.Session, logger log.Logger) ([]dbProxy, error) {
+	svc := rds.New(session)
+
+	var res []dbProxy
+	err := svc.DescribeDBProxiesPagesWithContext(ctx, &rds.DescribeDBProxiesInput{}, func(output *rds.DescribeDBProxiesOutput, lastPage bool) bool {
+		for _, p := range output.DBProxies {
+			res = append(res, dbProxy{
+				name:         aws.StringValue(p.DBProxyName),
+				engineFamily: aws.StringValue(p.EngineFamily),
+				status:       aws.StringValue(p.Status),
+				endpoint:     aws.StringValue(p.Endpoint),
+				requireTLS:   aws.BoolValue(p.RequireTLS),
+			})
+		}
+		return true // continue pagination
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res {
+		p := &res[i]
+		input := &rds.DescribeDBProxyTargetsInput{DBProxyName: aws.String(p.name)}
+		err = svc.DescribeDBProxyTargetsPagesWithContext(ctx, input, func(output *rds.DescribeDBProxyTargetsOutput, lastPage bool) bool {
+			for _, t := range output.Targets {
+				target := dbProxyTarget{
+					id:   aws.StringValue(t.RdsResourceId),
+					typ:  aws.StringValue(t.Type),
+					role: aws.StringValue(t.Role),
+				}
+				if t.TargetHealth != nil {
+					target.state = aws.StringValue(t.TargetHealth.State)
+					target.reason = aws.StringValue(t.TargetHealth.Reason)
+				}
+				p.targets = append(p.targets, target)
+			}
+			return true // continue pagination
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", fmt.Sprintf("Failed to get targets of proxy %s.", p.name), "error", err)
+			p.targets = nil
+		}
+	}
+	return res, nil
+}
+
+// targetDimensions represents ProxyName, TargetGroup, and Target dimensions values of a single proxy target.
+type targetDimensions struct {
+	proxy       string
+	targetGroup string
+	target      string
+}
+
+// listTargets returns dimensions of proxy targets with recently active target metrics.
+// Target dimension values are not documented, so they are discovered instead of being built from DescribeDBProxyTargets output.
+func listTargets(ctx context.Context, session *session.Session) ([]targetDimensions, error) {
+	names := make(map[string]struct{}, len(TargetMetrics))
+	for _, m := range TargetMetrics {
+		names[m.cwName] = struct{}{}
+	}
+
+	input := &cloudwatch.ListMetricsInput{
+		Namespace:      aws.String("AWS/RDS"),
+		Dimensions:     []*cloudwatch.DimensionFilter{{Name: aws.String("ProxyName")}, {Name: aws.String("Target")}},
+		RecentlyActive: aws.String("PT3H"),
+	}
+	var res []targetDimensions
+	seen := make(map[targetDimensions]struct{})
+	err := cloudwatch.New(session).ListMetricsPagesWithContext(ctx, input, func(output *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		for _, m := range output.Metrics {
+			if _, ok := names[aws.StringValue(m.MetricName)]; !ok || len(m.Dimensions) != 3 {
+				continue
+			}
+			var d targetDimensions
+			for _, dim := range m.Dimensions {
+				switch aws.StringValue(dim.Name) {
+				case "ProxyName":
+					d.proxy = aws.StringValue(dim.Value)
+				case "TargetGroup":
+					d.targetGroup = aws.StringValue(dim.Value)
+				case "Target":
+					d.target = aws.StringValue(dim.Value)
+				}
+			}
+			if d.proxy == "" || d.targetGroup == "" || d.target == "" {
+				continue
+			}
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = struct{}{}
+			res = append(res, d)
+		}
+		return true // continue pagination
+	})
+	return res, err
+}
@@ -0,0 +1,95 @@
+package proxy
+
+// Metric represents a single CloudWatch metric of RDS Proxy.
+type Metric struct {
+	cwName         string
+	prometheusName string
+	prometheusHelp string
+	statistic      string
+}
+
+// Metrics are requested once per proxy with ProxyName dimension.
+var Metrics = []Metric{
+	{
+		cwName:         "ClientConnections",
+		prometheusName: "aws_rds_proxy_client_connections_sum",
+		prometheusHelp: "The current number of client connections. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "ClientConnectionsReceived",
+		prometheusName: "aws_rds_proxy_client_connections_received_sum",
+		prometheusHelp: "The number of client connection requests received. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "ClientConnectionsSetupFailedAuth",
+		prometheusName: "aws_rds_proxy_client_connections_setup_failed_auth_sum",
+		prometheusHelp: "The number of client connection attempts that failed due to misconfigured authentication or TLS. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "DatabaseConnections",
+		prometheusName: "aws_rds_proxy_database_connections_sum",
+		prometheusHelp: "The current number of database connections. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "DatabaseConnectionsBorrowLatency",
+		prometheusName: "aws_rds_proxy_database_connections_borrow_latency_average",
+		prometheusHelp: "The time that it takes for the proxy to get a database connection. Units: Microseconds",
+		statistic:      "Average",
+	},
+	{
+		cwName:         "DatabaseConnectionsCurrentlyBorrowed",
+		prometheusName: "aws_rds_proxy_database_connections_currently_borrowed_sum",
+		prometheusHelp: "The current number of database connections in the borrow state. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "DatabaseConnectionsCurrentlyInTransaction",
+		prometheusName: "aws_rds_proxy_database_connections_currently_in_transaction_sum",
+		prometheusHelp: "The current number of database connections in a transaction. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "DatabaseConnectionsCurrentlySessionPinned",
+		prometheusName: "aws_rds_proxy_database_connections_currently_session_pinned_sum",
+		prometheusHelp: "The current number of database connections currently pinned because of operations in client requests that change session state. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "DatabaseConnectionsSetupFailed",
+		prometheusName: "aws_rds_proxy_database_connections_setup_failed_sum",
+		prometheusHelp: "The number of database connection requests that failed. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "MaxDatabaseConnectionsAllowed",
+		prometheusName: "aws_rds_proxy_max_database_connections_allowed_sum",
+		prometheusHelp: "The maximum number of database connections allowed. Units: Count",
+		statistic:      "Sum",
+	},
+	{
+		cwName:         "QueryDatabaseResponseLatency",
+		prometheusName: "aws_rds_proxy_query_database_response_latency_average",
+		prometheusHelp: "The time that the database took to respond to the query. Units: Microseconds",
+		statistic:      "Average",
+	},
+	{
+		cwName:         "QueryRequests",
+		prometheusName: "aws_rds_proxy_query_requests_sum",
+		prometheusHelp: "The number of queries received. Units: Count",
+		statistic:      "Sum",
+	},
+}
+
+// TargetMetrics are requested once per proxy target with ProxyName, TargetGroup, and Target dimensions.
+var TargetMetrics = []Metric{
+	{
+		cwName:         "AvailabilityPercentage",
+		prometheusName: "aws_rds_proxy_target_availability_percentage_average",
+		prometheusHelp: "The percentage of time for which the target was available. Units: Percent",
+		statistic:      "Average",
+	},
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/duyhai-bic/rds_exporter/basic"
+	"github.com/duyhai-bic/rds_exporter/client"
+	"github.com/duyhai-bic/rds_exporter/sessions"
+)
+
+const (
+	infoName = "aws_rds_proxy_info"
+	infoHelp = "RDS Proxy information; value is always 1."
+
+	targetHealthName = "aws_rds_proxy_target_health"
+	targetHealthHelp = "RDS Proxy target health state; value is always 1."
+)
+
+var (
+	infoLabels         = []string{"region", "proxy", "engine_family", "status", "endpoint", "require_tls"}
+	targetHealthLabels = []string{"region", "proxy", "target", "type", "role", "state", "reason"}
+)
+
+// query is a single metric of a single proxy or proxy target.
+type query struct {
+	key    string // cache key
+	metric Metric
+	labels prometheus.Labels
+}
+
+// scraper retrieves metrics of all proxies available to a single session.
+type scraper struct {
+	session        *session.Session
+	region         string
+	accountID      string // empty if not resolved
+	accountIDLabel bool   // add account_id label to metrics
+	pool           *basic.Pool
+	logger         log.Logger
+}
+
+func newScraper(session *session.Session, region, accountID string, accountIDLabel bool, pool *basic.Pool, logger log.Logger) *scraper {
+	return &scraper{
+		session:        session,
+		region:         region,
+		accountID:      accountID,
+		accountIDLabel: accountIDLabel,
+		pool:           pool,
+		logger:         logger,
+	}
+}
+
+// newScrapers returns scrapers for given sessions and their instances.
+// Proxies are not tied to instances, so sessions with the same region and resolved account
+// (for example, with different roles) are deduplicated and scraped once.
+// Sessions of unresolved accounts are all scraped, as they can belong to different accounts.
+func newScrapers(all map[*session.Session][]sessions.Instance, pool *basic.Pool, logger log.Logger) []*scraper {
+	res := make([]*scraper, 0, len(all))
+	seen := make(map[string]*scraper) // region/account => scraper
+	for session, instances := range all {
+		if len(instances) == 0 {
+			continue
+		}
+
+		// session's region and account are the same for all instances
+		var accountIDLabel bool
+		for _, i := range instances {
+			accountIDLabel = accountIDLabel || i.AccountIDLabel
+		}
+		s := newScraper(session, instances[0].Region, instances[0].AccountID, accountIDLabel, pool, logger)
+
+		if s.accountID != "" {
+			if prev := seen[s.sessionKey()]; prev != nil {
+				prev.accountIDLabel = prev.accountIDLabel || s.accountIDLabel
+				continue
+			}
+			seen[s.sessionKey()] = s
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// sessionKey returns region and account ID of the session.
+func (s *scraper) sessionKey() string {
+	return s.region + "/" + s.accountID
+}
+
+// cacheKey returns cache key for given proxy.
+func (s *scraper) cacheKey(proxy string) string {
+	return s.sessionKey() + "/" + proxy
+}
+
+// labels returns constant labels for CloudWatch metrics of given proxy.
+func (s *scraper) labels(proxy string) prometheus.Labels {
+	res := prometheus.Labels{
+		"region": s.region,
+		"proxy":  proxy,
+	}
+	if s.accountIDLabel && s.accountID != "" {
+		res["account_id"] = s.accountID
+	}
+	return res
+}
+
+// scrape returns metrics of all proxies keyed by cache key.
+// It returns an error only if proxies can't be described; CloudWatch errors are logged.
+func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, error) {
+	ctx = client.WithCollector(ctx, "proxy")
+
+	proxies, err := describeProxies(ctx, s.session, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string][]prometheus.Metric, len(proxies))
+	var queries []query
+	known := make(map[string]struct{}, len(proxies))
+	for _, p := range proxies {
+		key := s.cacheKey(p.name)
+		known[p.name] = struct{}{}
+
+		res[key] = append(res[key], s.constMetric(infoName, infoHelp, infoLabels,
+			s.region, p.name, strings.ToLower(p.engineFamily), p.status, p.endpoint, strconv.FormatBool(p.requireTLS)))
+		for _, t := range p.targets {
+			res[key] = append(res[key], s.constMetric(targetHealthName, targetHealthHelp, targetHealthLabels,
+				s.region, p.name, t.id, strings.ToLower(t.typ), strings.ToLower(t.role), strings.ToLower(t.state), t.reason))
+		}
+
+		for _, m := range Metrics {
+			queries = append(queries, query{key: key, metric: m, labels: s.labels(p.name)})
+		}
+	}
+
+	targets, err := listTargets(ctx, s.session)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "Failed to list proxy targets metrics.", "error", err)
+	}
+	for _, t := range targets {
+		if _, ok := known[t.proxy]; !ok {
+			continue
+		}
+		labels := s.labels(t.proxy)
+		labels["target_group"] = t.targetGroup
+		labels["target"] = t.target
+		for _, m := range TargetMetrics {
+			queries = append(queries, query{key: s.cacheKey(t.proxy), metric: m, labels: labels})
+		}
+	}
+
+	for key, metrics := range s.getMetricData(ctx, queries) {
+		res[key] = append(res[key], metrics...)
+	}
+	return res, nil
+}
+
+// constMetric returns metric with value 1 and given labels; account_id label is added if it is requested and resolved.
+func (s *scraper) constMetric(name, help string, labelNames []string, labelValues ...string) prometheus.Metric {
+	var constLabels prometheus.Labels
+	if s.accountIDLabel && s.accountID != "" {
+		constLabels = prometheus.Labels{"account_id": s.accountID}
+	}
+	desc := prometheus.NewDesc(name, help, labelNames, constLabels)
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, labelValues...)
+}
+
+// getMetricData makes batched GetMetricData requests in the pool and returns metrics with the latest datapoints
+// keyed by cache key.
+func (s *scraper) getMetricData(ctx context.Context, queries []query) map[string][]prometheus.Metric {
+	input := make([]*cloudwatch.MetricDataQuery, len(queries))
+	for i, q := range queries {
+		input[i] = q.metricDataQuery(fmt.Sprintf("m%d", i))
+	}
+	points := s.pool.GetMetricData(ctx, cloudwatch.New(s.session), input, false, s.logger)
+
+	res := make(map[string][]prometheus.Metric)
+	for i, q := range queries {
+		dps := points[fmt.Sprintf("m%d", i)]
+		if len(dps) == 0 {
+			continue
+		}
+		desc := prometheus.NewDesc(q.metric.prometheusName, q.metric.prometheusHelp, nil, q.labels)
+		res[q.key] = append(res[q.key], prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, dps[0].Value))
+	}
+	return res
+}
+
+// metricDataQuery returns GetMetricData query with given ID.
+func (q query) metricDataQuery(id string) *cloudwatch.MetricDataQuery {
+	dimensions := []*cloudwatch.Dimension{{Name: aws.String("ProxyName"), Value: aws.String(q.labels["proxy"])}}
+	if target := q.labels["target"]; target != "" {
+		dimensions = append(dimensions,
+			&cloudwatch.Dimension{Name: aws.String("TargetGroup"), Value: aws.String(q.labels["target_group"])},
+			&cloudwatch.Dimension{Name: aws.String("Target"), Value: aws.String(target)},
+		)
+	}
+
+	return &cloudwatch.MetricDataQuery{
+		Id: aws.String(id),
+		MetricStat: &cloudwatch.MetricStat{
+			Metric: &cloudwatch.Metric{
+				Namespace:  aws.String("AWS/RDS"),
+				MetricName: aws.String(q.metric.cwName),
+				Dimensions: dimensions,
+			},
+			Period: aws.Int64(int64(basic.Period.Seconds())),
+			Stat:   aws.String(q.metric.statistic),
+		},
+		ReturnData: aws.Bool(true),
+	}
+}
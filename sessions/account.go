@@ -0,0 +1,111 @@
+package sessions
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	"github.com/duyhai-bic/rds_exporter/client"
+)
+
+const (
+	// minAccountRetry and maxAccountRetry limit the delay before the next attempt to resolve account ID after failures;
+	// it is doubled after each failure, so sessions without reachable STS endpoint do not block every re-creation.
+	minAccountRetry = 5 * time.Minute
+	maxAccountRetry = time.Hour
+)
+
+// accountCache caches AWS account IDs resolved via sts:GetCallerIdentity.
+//
+// Sessions are re-created periodically, so the cache is shared between them to avoid
+// calling STS every time. Account ID is resolved again only when credentials change.
+// Failures are cached too, and the next attempt is made only after a backoff.
+type accountCache struct {
+	rw      sync.RWMutex
+	entries map[string]accountEntry // session key => entry
+}
+
+type accountEntry struct {
+	accessKeyID string
+	accountID   string
+
+	err     error         // the last failure; nil if account ID is resolved
+	retryAt time.Time     // time of the next attempt after failure
+	backoff time.Duration // delay before the next attempt after the last failure
+}
+
+var accounts = &accountCache{
+	entries: make(map[string]accountEntry),
+}
+
+// resolve returns account ID for given session, calling STS only if credentials were changed since the last call.
+// After a failure, cached error is returned until the backoff expires.
+func (c *accountCache) resolve(key string, s *session.Session) (string, error) {
+	c.rw.RLock()
+	e, ok := c.entries[key]
+	c.rw.RUnlock()
+	if ok && e.err != nil && time.Now().Before(e.retryAt) {
+		return "", fmt.Errorf("%w (next attempt after %s)", e.err, e.retryAt.Format(time.RFC3339))
+	}
+
+	creds, err := s.Config.Credentials.Get()
+	if err == nil && ok && e.err == nil && e.accessKeyID == creds.AccessKeyID {
+		return e.accountID, nil
+	}
+
+	var output *sts.GetCallerIdentityOutput
+	if err == nil {
+		output, err = sts.New(s).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	}
+
+	c.rw.Lock()
+	defer c.rw.Unlock()
+
+	if err != nil {
+		backoff := minAccountRetry
+		if e.err != nil {
+			backoff = e.backoff * 2
+			if backoff > maxAccountRetry {
+				backoff = maxAccountRetry
+			}
+		}
+		c.entries[key] = accountEntry{
+			err:     err,
+			retryAt: time.Now().Add(backoff),
+			backoff: backoff,
+		}
+		return "", err
+	}
+
+	accountID := aws.StringValue(output.Account)
+	c.entries[key] = accountEntry{
+		accessKeyID: creds.AccessKeyID,
+		accountID:   accountID,
+	}
+	return accountID, nil
+}
+
+// get returns cached account ID for given session key, or empty string.
+func (c *accountCache) get(key string) string {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.entries[key].accountID
+}
+
+// accountHandler returns AWS SDK handler that adds cached account ID to HTTP request context
+// for client metrics and rate limits.
+func accountHandler(key string) request.NamedHandler {
+	return request.NamedHandler{
+		Name: "rds_exporter.AccountHandler",
+		Fn: func(r *request.Request) {
+			if accountID := accounts.get(key); accountID != "" {
+				r.HTTPRequest = r.HTTPRequest.WithContext(client.WithAccount(r.HTTPRequest.Context(), accountID))
+			}
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package sessions
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sessionsDesc = prometheus.NewDesc(
+		"rds_exporter_sessions",
+		"Number of AWS sessions in use.",
+		nil, nil,
+	)
+	instancesDesc = prometheus.NewDesc(
+		"rds_exporter_instances",
+		"Number of RDS instances in use by partition and region.",
+		[]string{"partition", "region"}, nil,
+	)
+	skippedDesc = prometheus.NewDesc(
+		"rds_exporter_instances_skipped",
+		"Number of configured or discovered RDS instances skipped due to errors during the last sessions update.",
+		nil, nil,
+	)
+)
+
+// Inventory exposes sizes of the current sessions pool.
+type Inventory struct {
+	rw sync.RWMutex
+	s  *Sessions
+}
+
+// NewInventory creates new Inventory for given sessions pool.
+func NewInventory(s *Sessions) *Inventory {
+	return &Inventory{
+		s: s,
+	}
+}
+
+// Update replaces sessions pool.
+func (i *Inventory) Update(s *Sessions) {
+	i.rw.Lock()
+	i.s = s
+	i.rw.Unlock()
+}
+
+// Describe implements prometheus.Collector.
+func (i *Inventory) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sessionsDesc
+	ch <- instancesDesc
+	ch <- skippedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (i *Inventory) Collect(ch chan<- prometheus.Metric) {
+	i.rw.RLock()
+	s := i.s
+	i.rw.RUnlock()
+
+	type key struct{ partition, region string }
+	instances := make(map[key]int)
+	for _, is := range s.sessions {
+		for _, instance := range is {
+			instances[key{instance.Partition, instance.Region}]++
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(len(s.sessions)))
+	for k, n := range instances {
+		ch <- prometheus.MustNewConstMetric(instancesDesc, prometheus.GaugeValue, float64(n), k.partition, k.region)
+	}
+	ch <- prometheus.MustNewConstMetric(skippedDesc, prometheus.GaugeValue, float64(s.skipped))
+}
+
+// check interfaces
+var (
+	_ prometheus.Collector = (*Inventory)(nil)
+)
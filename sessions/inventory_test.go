@@ -0,0 +1,50 @@
+package sessions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventory(t *testing.T) {
+	s1, s2 := new(session.Session), new(session.Session)
+	inventory := NewInventory(&Sessions{
+		sessions: map[*session.Session][]Instance{
+			s1: {
+				{Partition: "aws", Region: "us-east-1", Instance: "db1"},
+				{Partition: "aws", Region: "us-east-1", Instance: "db2"},
+			},
+			s2: {
+				{Partition: "aws-us-gov", Region: "us-gov-west-1", Instance: "db3"},
+			},
+		},
+		skipped: 1,
+	})
+
+	expected := `
+# HELP rds_exporter_instances Number of RDS instances in use by partition and region.
+# TYPE rds_exporter_instances gauge
+rds_exporter_instances{partition="aws",region="us-east-1"} 2
+rds_exporter_instances{partition="aws-us-gov",region="us-gov-west-1"} 1
+# HELP rds_exporter_instances_skipped Number of configured or discovered RDS instances skipped due to errors during the last sessions update.
+# TYPE rds_exporter_instances_skipped gauge
+rds_exporter_instances_skipped 1
+# HELP rds_exporter_sessions Number of AWS sessions in use.
+# TYPE rds_exporter_sessions gauge
+rds_exporter_sessions 2
+`
+	require.NoError(t, testutil.CollectAndCompare(inventory, strings.NewReader(expected)))
+
+	inventory.Update(&Sessions{})
+	require.NoError(t, testutil.CollectAndCompare(inventory, strings.NewReader(`
+# HELP rds_exporter_instances_skipped Number of configured or discovered RDS instances skipped due to errors during the last sessions update.
+# TYPE rds_exporter_instances_skipped gauge
+rds_exporter_instances_skipped 0
+# HELP rds_exporter_sessions Number of AWS sessions in use.
+# TYPE rds_exporter_sessions gauge
+rds_exporter_sessions 0
+`)))
+}
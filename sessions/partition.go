@@ -0,0 +1,57 @@
+package sessions
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// partitionFor returns AWS partition ID (aws, aws-cn, aws-us-gov, etc.) for given region.
+func partitionFor(region string) (string, error) {
+	p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return "", fmt.Errorf("unknown AWS region %q", region)
+	}
+	return p.ID(), nil
+}
+
+// checkPartition checks that given instance configuration is valid for the region's partition.
+func checkPartition(instance config.Instance, partition string) error {
+	if instance.AWSRoleArn != "" {
+		a, err := arn.Parse(instance.AWSRoleArn)
+		if err != nil {
+			return err
+		}
+		if a.Partition != partition {
+			return fmt.Errorf("role %s belongs to partition %q, but region %s belongs to partition %q",
+				instance.AWSRoleArn, a.Partition, instance.Region, partition)
+		}
+	}
+
+	// FIPS endpoints exist only in aws and aws-us-gov partitions
+	if instance.UseFIPSEndpoint && partition == endpoints.AwsCnPartitionID {
+		return fmt.Errorf("FIPS endpoints are not available in partition %q", partition)
+	}
+
+	return nil
+}
+
+// endpointConfig returns AWS configuration with region and endpoint options for given instance.
+// It is used for all sessions, including ones used for STS calls.
+func endpointConfig(instance config.Instance) *aws.Config {
+	cfg := aws.NewConfig().WithRegion(instance.Region)
+	if instance.UseFIPSEndpoint {
+		cfg.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
+	}
+	if instance.UseDualStackEndpoint {
+		cfg.UseDualStackEndpoint = endpoints.DualStackEndpointStateEnabled
+	}
+	if instance.STSRegionalEndpoint {
+		cfg.STSRegionalEndpoint = endpoints.RegionalSTSEndpoint
+	}
+	return cfg
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 
+	"github.com/duyhai-bic/rds_exporter/client"
 	"github.com/duyhai-bic/rds_exporter/config"
 	"github.com/duyhai-bic/rds_exporter/discovery"
 )
@@ -28,6 +29,14 @@ type Instance struct {
 	ResourceID                 string
 	Labels                     map[string]string
 	EnhancedMonitoringInterval time.Duration
+	AccountID                  string // empty if not resolved
+	AccountIDLabel             bool   // add account ID to metrics
+	Partition                  string
+	Cluster                    string  // DB cluster identifier; empty for instances that are not cluster members
+	Engine                     string  // aurora-mysql, postgres, sqlserver-ee, etc.
+	InstanceClass              string  // db.r6g.large, db.serverless, etc.
+	MinCapacity                float64 // Aurora Serverless v2 minimal capacity of the cluster, in ACUs
+	MaxCapacity                float64 // Aurora Serverless v2 maximal capacity of the cluster, in ACUs; zero if not configured
 }
 
 func (i Instance) String() string {
@@ -39,29 +48,76 @@ func (i Instance) String() string {
 	return res
 }
 
+// Serverless returns true for Aurora Serverless v2 instances.
+func (i Instance) Serverless() bool {
+	return i.InstanceClass == "db.serverless"
+}
+
+// MetricLabels returns extra labels for all instance metrics:
+// account ID (if requested and resolved) and labels from configuration file, which take precedence.
+func (i Instance) MetricLabels() map[string]string {
+	if !i.AccountIDLabel || i.AccountID == "" {
+		return i.Labels
+	}
+
+	res := make(map[string]string, len(i.Labels)+1)
+	res["account_id"] = i.AccountID
+	for n, v := range i.Labels {
+		res[n] = v
+	}
+	return res
+}
+
 // Sessions is a pool of AWS sessions.
 type Sessions struct {
 	sessions map[*session.Session][]Instance
+	skipped  int // number of instances skipped due to errors
 }
 
 // New creates a new sessions pool for given configuration.
-func New(instances []config.Instance, client *http.Client, logger log.Logger, trace bool) (*Sessions, error) {
+func New(instances []config.Instance, client *client.Client, logger log.Logger, trace TraceOptions) (*Sessions, error) {
 	logger = log.With(logger, "component", "sessions")
 	level.Info(logger).Log("msg", "Creating sessions...")
 	res := &Sessions{
 		sessions: make(map[*session.Session][]Instance),
 	}
 
-	sharedSessions := make(map[string]*session.Session) // region/key => session
+	sharedSessions := make(map[string]*session.Session) // region/key/role => session
+	accountIDs := make(map[string]string)               // region/key/role => account ID
+
+	// account ID is resolved once per session, and only if it is used by account_id_label or rate limits
+	accountRequired := make(map[string]bool) // region/key/role => true
+	for _, instance := range instances {
+		if instance.AccountIDLabel || client.AccountRateLimits() {
+			accountRequired[sessionKey(instance)] = true
+		}
+	}
+
+	traceRequest, traceResponse := traceHandlers(trace, logger)
 	for _, instance := range instances {
-		// re-use session for the same region and key (explicit or empty for implicit) pair
-		if s := sharedSessions[instance.Region+"/"+instance.AWSAccessKey]; s != nil {
+		partition, err := partitionFor(instance.Region)
+		if err == nil {
+			err = checkPartition(instance, partition)
+		}
+		if err != nil {
+			level.Error(logger).Log("msg", fmt.Sprintf("Skipping %s.", instance), "error", err)
+			res.skipped++
+			continue
+		}
+
+		key := sessionKey(instance)
+
+		// re-use session for the same region, key (explicit or empty for implicit) and role
+		if s := sharedSessions[key]; s != nil {
 			res.sessions[s] = append(res.sessions[s], Instance{
 				Region:                 instance.Region,
 				Instance:               instance.Instance,
 				Labels:                 instance.Labels,
 				DisableBasicMetrics:    instance.DisableBasicMetrics,
 				DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
+				AccountID:              accountIDs[key],
+				AccountIDLabel:         instance.AccountIDLabel,
+				Partition:              partition,
 			})
 			continue
 		}
@@ -69,38 +125,29 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		// use given credentials, or default credential chain
 		var creds *credentials.Credentials
 
-		creds, err := buildCredentials(instance)
+		creds, err = buildCredentials(instance, client)
 
 		if err != nil {
 			return nil, err
 		}
 
 		// make config with careful logging
-		awsCfg := &aws.Config{
-			Credentials: creds,
-			Region:      aws.String(instance.Region),
-			HTTPClient:  client,
-		}
-		if trace {
-			// fail-safe
-			if _, ok := os.LookupEnv("CI"); ok {
-				panic("Do not enable AWS request tracing on CI - output will contain credentials.")
-			}
-
-			awsCfg.Logger = aws.LoggerFunc(func(args ...interface{}) {
-				level.Debug(logger).Log("msg", args)
-			})
+		awsCfg := endpointConfig(instance).WithCredentials(creds)
+		if trace.Enabled {
 			awsCfg.CredentialsChainVerboseErrors = aws.Bool(true)
-			level := aws.LogDebugWithSigning | aws.LogDebugWithHTTPBody
-			level |= aws.LogDebugWithRequestRetries | aws.LogDebugWithRequestErrors | aws.LogDebugWithEventStreamBody
-			awsCfg.LogLevel = aws.LogLevel(level)
 		}
 
 		// store session
-		s, err := session.NewSession(awsCfg)
+		s, err := newSession(awsCfg, client)
 		if err != nil {
 			return nil, err
 		}
+		s.Handlers.Send.PushFrontNamed(accountHandler(key))
+		if trace.Enabled && trace.Instance == "" {
+			s.Handlers.Send.PushFrontNamed(traceRequest)
+			s.Handlers.Send.PushBackNamed(traceResponse)
+		}
+
 		// Discover rds instances if no instance specified
 		discoveredInstances := []string{}
 		if instance.Instance == "" {
@@ -111,7 +158,10 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		} else {
 			discoveredInstances = append(discoveredInstances, instance.Instance)
 		}
-		sharedSessions[instance.Region+"/"+instance.AWSAccessKey] = s
+		sharedSessions[key] = s
+		if accountRequired[key] {
+			accountIDs[key] = resolveAccountID(key, s, instance, logger)
+		}
 		for _, identifier := range discoveredInstances {
 			res.sessions[s] = append(res.sessions[s], Instance{
 				Region:                 instance.Region,
@@ -119,6 +169,9 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 				Labels:                 instance.Labels,
 				DisableBasicMetrics:    instance.DisableBasicMetrics,
 				DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
+				AccountID:              accountIDs[key],
+				AccountIDLabel:         instance.AccountIDLabel,
+				Partition:              partition,
 			})
 		}
 	}
@@ -141,6 +194,9 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 					if *dbInstance.DBInstanceIdentifier == instance.Instance {
 						instances[i].ResourceID = *dbInstance.DbiResourceId
 						instances[i].EnhancedMonitoringInterval = time.Duration(*dbInstance.MonitoringInterval) * time.Second
+						instances[i].Cluster = aws.StringValue(dbInstance.DBClusterIdentifier)
+						instances[i].Engine = aws.StringValue(dbInstance.Engine)
+						instances[i].InstanceClass = aws.StringValue(dbInstance.DBInstanceClass)
 					}
 				}
 			}
@@ -150,12 +206,20 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		}
 	}
 
+	// add Aurora Serverless v2 scaling configuration to cluster instances
+	for session, instances := range res.sessions {
+		if err := addScalingConfiguration(rds.New(session), instances); err != nil {
+			level.Error(logger).Log("msg", "Failed to get clusters scaling configuration.", "error", err)
+		}
+	}
+
 	// remove instances without resource ID
 	for session, instances := range res.sessions {
 		newInstances := make([]Instance, 0, len(instances))
 		for _, instance := range instances {
 			if instance.ResourceID == "" {
 				level.Error(logger).Log("msg", fmt.Sprintf("Skipping %s - can't determine resourceID.", instance))
+				res.skipped++
 				continue
 			}
 			newInstances = append(newInstances, instance)
@@ -170,11 +234,23 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		}
 	}
 
+	// trace requests of session used by given instance; it is known only after discovery
+	if trace.Enabled && trace.Instance != "" {
+		for s, instances := range res.sessions {
+			if hasInstance(instances, trace.Instance) {
+				s.Handlers.Send.PushFrontNamed(traceRequest)
+				s.Handlers.Send.PushBackNamed(traceResponse)
+			}
+		}
+	}
+
 	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "Region\tInstance\tResource ID\tInterval\n")
+	fmt.Fprintf(w, "Partition\tRegion\tAccount\tInstance\tEngine\tClass\tResource ID\tInterval\n")
 	for _, instances := range res.sessions {
 		for _, instance := range instances {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", instance.Region, instance.Instance, instance.ResourceID, instance.EnhancedMonitoringInterval)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				instance.Partition, instance.Region, instance.AccountID, instance.Instance, instance.Engine, instance.InstanceClass,
+				instance.ResourceID, instance.EnhancedMonitoringInterval)
 		}
 	}
 	_ = w.Flush()
@@ -183,6 +259,33 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 	return res, nil
 }
 
+// addScalingConfiguration sets Aurora Serverless v2 capacity range of cluster instances.
+func addScalingConfiguration(svc *rds.RDS, instances []Instance) error {
+	clusters := make(map[string][]int) // cluster identifier => instance indexes
+	for i, instance := range instances {
+		if instance.Cluster != "" {
+			clusters[instance.Cluster] = append(clusters[instance.Cluster], i)
+		}
+	}
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	return svc.DescribeDBClustersPages(&rds.DescribeDBClustersInput{}, func(output *rds.DescribeDBClustersOutput, lastPage bool) bool {
+		for _, cluster := range output.DBClusters {
+			scaling := cluster.ServerlessV2ScalingConfiguration
+			if scaling == nil {
+				continue
+			}
+			for _, i := range clusters[aws.StringValue(cluster.DBClusterIdentifier)] {
+				instances[i].MinCapacity = aws.Float64Value(scaling.MinCapacity)
+				instances[i].MaxCapacity = aws.Float64Value(scaling.MaxCapacity)
+			}
+		}
+		return true // continue pagination
+	})
+}
+
 // GetSession returns session and full instance information for given region and instance.
 func (s *Sessions) GetSession(region, instance string) (*session.Session, *Instance) {
 	for session, instances := range s.sessions {
@@ -195,15 +298,58 @@ func (s *Sessions) GetSession(region, instance string) (*session.Session, *Insta
 	return nil, nil
 }
 
-func buildCredentials(instance config.Instance) (*credentials.Credentials, error) {
+// hasInstance returns true if instances contain one with given identifier or resource ID.
+func hasInstance(instances []Instance, instance string) bool {
+	for _, i := range instances {
+		if i.Instance == instance || i.ResourceID == instance {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionKey returns a key for sharing session between instances with the same region, key, role and endpoint options.
+func sessionKey(instance config.Instance) string {
+	return fmt.Sprintf("%s/%s/%s/fips=%t/dualstack=%t/sts=%t", instance.Region, instance.AWSAccessKey, instance.AWSRoleArn,
+		instance.UseFIPSEndpoint, instance.UseDualStackEndpoint, instance.STSRegionalEndpoint)
+}
+
+// resolveAccountID returns AWS account ID for given session, or empty string if it can't be resolved.
+func resolveAccountID(key string, s *session.Session, instance config.Instance, logger log.Logger) string {
+	accountID, err := accounts.resolve(key, s)
+	if err != nil {
+		level.Error(logger).Log("msg", fmt.Sprintf("Failed to resolve account ID for %s.", instance), "error", err)
+	}
+	return accountID
+}
+
+// newSession creates AWS session that uses exporter's HTTP client and retryer for all requests,
+// including STS requests made by credential providers.
+//
+// AWS SDK loads CA bundle from AWS_CA_BUNDLE environment variable into the transport of session's HTTP client,
+// and fails for exporter's transport that loads it itself (see config.HTTPClient). So session is created with
+// a placeholder client that is replaced in place afterwards, and all copies of session configuration see the change.
+func newSession(cfg *aws.Config, client *client.Client) (*session.Session, error) {
+	httpClient := new(http.Client)
+	cfg = cfg.Copy().WithHTTPClient(httpClient)
+	cfg.Retryer = client.Retryer()
+
+	s, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	*httpClient = *client.HTTP()
+	s.Handlers.Send.PushFrontNamed(client.AttemptHandler())
+	return s, nil
+}
+
+func buildCredentials(instance config.Instance, client *client.Client) (*credentials.Credentials, error) {
 	// If IRSA is enabled, let the AWS SDK use the default credential provider chain,
 	// which includes the service account role credentials.
 	if instance.IRSAEnabled {
 		// Create a new session with just the region set, no credentials provided explicitly.
 		// This allows the SDK to use the credentials mounted by IRSA.
-		stsSession, err := session.NewSession(&aws.Config{
-			Region: aws.String(instance.Region),
-		})
+		stsSession, err := newSession(endpointConfig(instance), client)
 		if err != nil {
 			return nil, err
 		}
@@ -212,10 +358,8 @@ func buildCredentials(instance config.Instance) (*credentials.Credentials, error
 	}
 
 	if instance.AWSRoleArn != "" {
-		stsSession, err := session.NewSession(&aws.Config{
-			Region:      aws.String(instance.Region),
-			Credentials: credentials.NewStaticCredentials(instance.AWSAccessKey, instance.AWSSecretKey, ""),
-		})
+		stsSession, err := newSession(endpointConfig(instance).
+			WithCredentials(credentials.NewStaticCredentials(instance.AWSAccessKey, instance.AWSSecretKey, "")), client)
 		if err != nil {
 			return nil, err
 		}
@@ -231,10 +375,7 @@ func buildCredentials(instance config.Instance) (*credentials.Credentials, error
 		}), nil
 	}
 	// Use the default credential provider chain, which includes the service account role credentials.
-	stsSession, err := session.NewSession(&aws.Config{
-		Region:                        aws.String(instance.Region),
-		CredentialsChainVerboseErrors: aws.Bool(true),
-	})
+	stsSession, err := newSession(endpointConfig(instance).WithCredentialsChainVerboseErrors(true), client)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,191 @@
+package sessions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// TraceOptions configures AWS requests tracing.
+type TraceOptions struct {
+	Enabled   bool
+	Instance  string // trace only requests of session used by this instance; empty for all sessions
+	Operation string // trace only this operation (GetMetricStatistics, FilterLogEvents, etc.); empty for all operations
+}
+
+// maxTraceBodySize limits the size of request and response bodies in traces.
+const maxTraceBodySize = 16 * 1024
+
+const redacted = "REDACTED"
+
+// redactedHeaders contain credentials and signatures.
+var redactedHeaders = map[string]struct{}{
+	"Authorization":        {},
+	"X-Amz-Security-Token": {},
+}
+
+// redactedParams are query string and form parameters that contain credentials and signatures.
+var redactedParams = map[string]struct{}{
+	"X-Amz-Security-Token": {},
+	"X-Amz-Credential":     {},
+	"X-Amz-Signature":      {},
+	"AWSAccessKeyId":       {},
+	"Signature":            {},
+	"SecurityToken":        {},
+}
+
+var (
+	// access key IDs: long-term (AKIA) and temporary (ASIA)
+	accessKeyRE = regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`)
+
+	// secrets in XML (query protocol) and JSON responses, for example, from sts:AssumeRole
+	xmlSecretRE  = regexp.MustCompile(`<(SecretAccessKey|SessionToken|SecurityToken)>[^<]*</`)
+	jsonSecretRE = regexp.MustCompile(`"(SecretAccessKey|SessionToken|SecurityToken|secretAccessKey|sessionToken)"\s*:\s*"[^"]*"`)
+)
+
+// redactBody removes credentials from request or response body, and truncates it.
+func redactBody(b []byte) string {
+	s := string(b)
+
+	if values, err := url.ParseQuery(s); err == nil && strings.Contains(s, "=") && !strings.ContainsAny(s, "{<") {
+		s = redactValues(values).Encode()
+	}
+
+	s = accessKeyRE.ReplaceAllString(s, redacted)
+	s = xmlSecretRE.ReplaceAllString(s, "<$1>"+redacted+"</")
+	s = jsonSecretRE.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+
+	if len(s) > maxTraceBodySize {
+		s = fmt.Sprintf("%s... (%d bytes total)", s[:maxTraceBodySize], len(s))
+	}
+	return s
+}
+
+// redactURL removes credentials from URL query string.
+func redactURL(u *url.URL) string {
+	c := *u
+	c.RawQuery = redactValues(u.Query()).Encode()
+	return accessKeyRE.ReplaceAllString(c.String(), redacted)
+}
+
+// redactValues returns a copy of values with credentials redacted.
+func redactValues(values url.Values) url.Values {
+	res := make(url.Values, len(values))
+	for k, vs := range values {
+		if _, ok := redactedParams[k]; ok {
+			res[k] = []string{redacted}
+			continue
+		}
+		res[k] = vs
+	}
+	return res
+}
+
+// redactHeaders formats headers with credentials redacted.
+func redactHeaders(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := strings.Join(h[k], ",")
+		if _, ok := redactedHeaders[http.CanonicalHeaderKey(k)]; ok {
+			v = redacted
+		}
+		parts = append(parts, k+": "+accessKeyRE.ReplaceAllString(v, redacted))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// traceHandlers returns AWS SDK handlers that log requests and responses with credentials redacted.
+// The first one should be added to the front of Send handlers, and the second one to the back.
+func traceHandlers(opts TraceOptions, logger log.Logger) (request.NamedHandler, request.NamedHandler) {
+	logger = log.With(logger, "component", "trace")
+
+	match := func(r *request.Request) bool {
+		return opts.Operation == "" || r.Operation.Name == opts.Operation
+	}
+
+	traceRequest := request.NamedHandler{
+		Name: "rds_exporter.TraceRequest",
+		Fn: func(r *request.Request) {
+			if !match(r) {
+				return
+			}
+
+			var body string
+			if r.Body != nil {
+				// read body and seek back to its start
+				if _, err := r.Body.Seek(r.BodyStart, io.SeekStart); err == nil {
+					b, _ := io.ReadAll(r.Body)
+					_, _ = r.Body.Seek(r.BodyStart, io.SeekStart)
+					body = redactBody(b)
+				}
+			}
+
+			level.Debug(logger).Log(
+				"msg", "AWS request",
+				"service", r.ClientInfo.ServiceName, "operation", r.Operation.Name, "attempt", r.RetryCount+1,
+				"method", r.HTTPRequest.Method, "url", redactURL(r.HTTPRequest.URL),
+				"headers", redactHeaders(r.HTTPRequest.Header), "body", body,
+			)
+		},
+	}
+
+	traceResponse := request.NamedHandler{
+		Name: "rds_exporter.TraceResponse",
+		Fn: func(r *request.Request) {
+			if !match(r) {
+				return
+			}
+
+			duration := time.Since(r.AttemptTime)
+			if r.HTTPResponse == nil {
+				level.Debug(logger).Log(
+					"msg", "AWS response",
+					"service", r.ClientInfo.ServiceName, "operation", r.Operation.Name, "attempt", r.RetryCount+1,
+					"duration", duration, "error", r.Error,
+				)
+				return
+			}
+
+			// read body and replace it so it still can be read by unmarshalers
+			b, err := io.ReadAll(r.HTTPResponse.Body)
+			r.HTTPResponse.Body = &bodyReadCloser{
+				Reader: io.MultiReader(bytes.NewReader(b), r.HTTPResponse.Body),
+				Closer: r.HTTPResponse.Body,
+			}
+
+			keyvals := []interface{}{
+				"msg", "AWS response",
+				"service", r.ClientInfo.ServiceName, "operation", r.Operation.Name, "attempt", r.RetryCount + 1,
+				"status", r.HTTPResponse.StatusCode, "duration", duration,
+				"headers", redactHeaders(r.HTTPResponse.Header), "body", redactBody(b),
+			}
+			if err != nil {
+				keyvals = append(keyvals, "error", err)
+			}
+			level.Debug(logger).Log(keyvals...)
+		},
+	}
+
+	return traceRequest, traceResponse
+}
+
+type bodyReadCloser struct {
+	io.Reader
+	io.Closer
+}
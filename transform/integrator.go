@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// bootTimeTolerance is the maximal difference of boot times of the same instance that is not considered a restart:
+	// boot time is computed from uptime and sample time, and both are not precise.
+	bootTimeTolerance = time.Minute
+
+	// maxSeriesAge is the maximal time since the last sample of the series after which its state is removed.
+	maxSeriesAge = time.Hour
+)
+
+// series is a state of a single series converted from rate to counter.
+type series struct {
+	total    float64
+	last     time.Time // time of the last sample
+	bootTime time.Time // zero if unknown
+}
+
+// Integrator accumulates per-second rates multiplied by time elapsed between samples into monotonic counters.
+// It is safe for concurrent use.
+type Integrator struct {
+	m         sync.Mutex
+	series    map[string]*series // series key => state
+	lastSweep time.Time
+}
+
+// NewIntegrator creates a new Integrator.
+func NewIntegrator() *Integrator {
+	return &Integrator{
+		series:    make(map[string]*series),
+		lastSweep: time.Now(),
+	}
+}
+
+// Add adds rate multiplied by time elapsed since the previous sample of the series to its total, and returns it.
+// The first sample of the series starts from zero; samples that are not newer than the previous one are not added.
+// The total is reset to zero when instance boot time (if known) changes, i.e. the instance was restarted.
+func (i *Integrator) Add(key string, rate float64, ts, bootTime time.Time) float64 {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	i.sweep(ts)
+
+	s := i.series[key]
+	switch {
+	case s == nil:
+		s = &series{last: ts, bootTime: bootTime}
+		i.series[key] = s
+		return s.total
+
+	case !bootTime.IsZero() && !s.bootTime.IsZero() && bootTime.Sub(s.bootTime) > bootTimeTolerance:
+		*s = series{last: ts, bootTime: bootTime}
+		return s.total
+	}
+
+	if ts.After(s.last) {
+		s.total += rate * ts.Sub(s.last).Seconds()
+		s.last = ts
+	}
+	if s.bootTime.IsZero() {
+		s.bootTime = bootTime
+	}
+	return s.total
+}
+
+// sweep removes states of series without samples for too long, so deleted instances do not leak memory.
+// It does that at most once per maxSeriesAge. It should be called with held lock.
+func (i *Integrator) sweep(now time.Time) {
+	if now.Sub(i.lastSweep) < maxSeriesAge {
+		return
+	}
+
+	for key, s := range i.series {
+		if now.Sub(s.last) > maxSeriesAge {
+			delete(i.series, key)
+		}
+	}
+	i.lastSweep = now
+}
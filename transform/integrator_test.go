@@ -0,0 +1,38 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrator(t *testing.T) {
+	i := NewIntegrator()
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	boot := ts.Add(-24 * time.Hour)
+
+	for _, step := range []struct {
+		rate     float64
+		ts       time.Time
+		bootTime time.Time
+		expected float64
+	}{
+		{10, ts, time.Time{}, 0},
+		{10, ts.Add(time.Minute), boot, 600},
+		{10, ts.Add(2 * time.Minute), boot.Add(30 * time.Second), 1200}, // imprecise boot time
+		{10, ts.Add(90 * time.Second), boot, 1200},                      // older sample
+		{1, ts.Add(3 * time.Minute), time.Time{}, 1260},                 // unknown boot time
+		{10, ts.Add(4 * time.Minute), ts.Add(3 * time.Minute), 0},       // restart
+		{2, ts.Add(5 * time.Minute), ts.Add(3 * time.Minute), 120},
+	} {
+		assert.Equal(t, step.expected, i.Add("a", step.rate, step.ts, step.bootTime), "%+v", step)
+	}
+
+	// series without samples for too long are removed
+	assert.Equal(t, 0.0, i.Add("b", 1, ts.Add(30*time.Minute), time.Time{}))
+	i.lastSweep = ts
+	assert.Equal(t, 2400.0, i.Add("b", 1, ts.Add(70*time.Minute), time.Time{}))
+	assert.Len(t, i.series, 1)
+	assert.Contains(t, i.series, "b")
+}
@@ -0,0 +1,161 @@
+// Package transform applies declarative transformation rules to basic and enhanced metrics.
+package transform
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// Defaults are built-in rules applied before rules from configuration file.
+//
+//nolint:lll
+var Defaults = []config.Transform{
+	// fake EngineUptime -> node_boot_time_seconds with timestamp - EngineUptime
+	{Collector: "basic", CloudWatchMetric: "EngineUptime", NowMinus: true},
+
+	// node_exporter-like metrics: kilobytes to bytes, and kilobytes to a number of 4k pages (what our dashboards assume)
+	{Collector: "enhanced", Metric: "node_memory_*_bytes", Scale: 1024},
+	{Collector: "enhanced", Metric: "node_vmstat_pswp*", Scale: 0.25},
+	{Collector: "enhanced", Metric: "node_disk_*_bytes_total", Scale: 1024},
+	{Collector: "enhanced", Metric: "node_filesystem_*", Scale: 1024},
+
+	// AWS gives us rates, node_exporter - total counters; integrate rates, so dashboards using rate() work;
+	// basic metrics are matched by CloudWatch name and statistic, as Prometheus names depend on statistic_label option
+	{Collector: "basic", CloudWatchMetric: "NetworkReceiveThroughput", Statistic: "Average", RateToCounter: true, Rename: "node_network_receive_bytes_total", Keep: true},
+	{Collector: "basic", CloudWatchMetric: "NetworkTransmitThroughput", Statistic: "Average", RateToCounter: true, Rename: "node_network_transmit_bytes_total", Keep: true},
+	{Collector: "basic", CloudWatchMetric: "ReadIOPS", Statistic: "Average", RateToCounter: true, Rename: "node_disk_reads_completed_total", Keep: true},
+	{Collector: "basic", CloudWatchMetric: "WriteIOPS", Statistic: "Average", RateToCounter: true, Rename: "node_disk_writes_completed_total", Keep: true},
+	{Collector: "basic", CloudWatchMetric: "ReadThroughput", Statistic: "Average", RateToCounter: true, Rename: "node_disk_read_bytes_total", Keep: true},
+	{Collector: "basic", CloudWatchMetric: "WriteThroughput", Statistic: "Average", RateToCounter: true, Rename: "node_disk_written_bytes_total", Keep: true},
+	{Collector: "enhanced", Metric: "node_network_*_bytes_total", RateToCounter: true},
+	{Collector: "enhanced", Metric: "node_disk_*_total", RateToCounter: true},
+}
+
+// Sample is a single metric value that rules are applied to.
+type Sample struct {
+	Collector        string // basic or enhanced
+	CloudWatchMetric string // empty for enhanced metrics
+	Statistic        string // CloudWatch statistic like Average or p99; empty for enhanced metrics
+	Name             string
+	Labels           map[string]string // all labels; they identify series for rate_to_counter
+	Value            float64
+	Timestamp        time.Time // current time is used if zero
+	BootTime         time.Time // instance boot time; rate_to_counter resets counters when it changes; zero if unknown
+	Counter          bool      // set by rate_to_counter
+}
+
+// Rules applies default and configured rules. It is safe for concurrent use.
+type Rules struct {
+	rules    []config.Transform
+	counters *Integrator
+}
+
+// New returns default rules followed by given rules, which should be checked.
+func New(rules []config.Transform) *Rules {
+	return &Rules{
+		rules:    append(append([]config.Transform(nil), Defaults...), rules...),
+		counters: NewIntegrator(),
+	}
+}
+
+// defaultRules are used by nil Rules.
+var defaultRules = New(nil)
+
+// matches returns true if rule should be applied to sample.
+func matches(t *config.Transform, s *Sample) bool {
+	if t.Collector != "" && t.Collector != s.Collector {
+		return false
+	}
+	if t.CloudWatchMetric != "" && t.CloudWatchMetric != s.CloudWatchMetric {
+		return false
+	}
+	if t.Statistic != "" && t.Statistic != s.Statistic {
+		return false
+	}
+	if t.Metric != "" {
+		if ok, _ := path.Match(t.Metric, s.Name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply applies all matching rules to sample in order, and returns resulting samples:
+// the given one, and copies made by rules with keep option. It also returns true if any rule matched.
+// Nil Rules apply default rules only.
+func (r *Rules) Apply(s Sample) ([]Sample, bool) {
+	if r == nil {
+		r = defaultRules
+	}
+
+	res := []Sample{s}
+
+	var applied bool
+	for i := range r.rules {
+		t := &r.rules[i]
+
+		// copies made by this rule are not matched again
+		for j, n := 0, len(res); j < n; j++ {
+			if !matches(t, &res[j]) {
+				continue
+			}
+			applied = true
+
+			if !t.Keep {
+				r.apply(t, &res[j])
+				continue
+			}
+			c := res[j]
+			c.Labels = make(map[string]string, len(res[j].Labels))
+			for n, v := range res[j].Labels {
+				c.Labels[n] = v
+			}
+			r.apply(t, &c)
+			res = append(res, c)
+		}
+	}
+	return res, applied
+}
+
+// apply applies a single rule to sample.
+func (r *Rules) apply(t *config.Transform, s *Sample) {
+	ts := s.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	if t.NowMinus {
+		s.Value = float64(ts.Unix() - int64(s.Value))
+	}
+	if t.Scale != 0 {
+		s.Value *= t.Scale
+	}
+	s.Value += t.Offset
+	if t.RateToCounter {
+		// use the new name, so counters made from the same metric by several rules do not share state
+		name := s.Name
+		if t.Rename != "" {
+			name = t.Rename
+		}
+		s.Value = r.counters.Add(seriesKey(s.Collector, name, s.Labels), s.Value, ts, s.BootTime)
+		s.Counter = true
+	}
+	if t.Rename != "" {
+		s.Name = t.Rename
+	}
+}
+
+// seriesKey returns a key that identifies series of given collector with given name and labels.
+func seriesKey(collector, name string, labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for n, v := range labels {
+		pairs = append(pairs, n+"="+strconv.Quote(v))
+	}
+	sort.Strings(pairs)
+	return collector + "/" + name + "{" + strings.Join(pairs, ",") + "}"
+}
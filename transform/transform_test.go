@@ -0,0 +1,133 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duyhai-bic/rds_exporter/config"
+)
+
+// apply applies rules to a single sample and returns the only resulting sample.
+func apply(t *testing.T, r *Rules, s Sample) (Sample, bool) {
+	t.Helper()
+
+	res, applied := r.Apply(s)
+	require.Len(t, res, 1)
+	return res[0], applied
+}
+
+func TestApply(t *testing.T) {
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Defaults", func(t *testing.T) {
+		r := New(nil)
+
+		s, applied := apply(t, r, Sample{Collector: "basic", CloudWatchMetric: "EngineUptime", Name: "node_boot_time_seconds", Value: 60, Timestamp: ts})
+		assert.True(t, applied)
+		assert.Equal(t, float64(ts.Unix()-60), s.Value)
+
+		s, applied = apply(t, r, Sample{Collector: "enhanced", Name: "node_memory_MemFree_bytes", Value: 2})
+		assert.True(t, applied)
+		assert.Equal(t, 2048.0, s.Value)
+
+		s, applied = apply(t, r, Sample{Collector: "enhanced", Name: "node_memory_HugePages_Free", Value: 2})
+		assert.False(t, applied)
+		assert.Equal(t, 2.0, s.Value)
+
+		// enhanced rules are not applied to basic metrics
+		_, applied = apply(t, r, Sample{Collector: "basic", CloudWatchMetric: "FreeableMemory", Name: "node_memory_MemFree_bytes", Value: 2})
+		assert.False(t, applied)
+
+		// rates are kept, and counters are added
+		labels := map[string]string{"instance": "db1"}
+		res, applied := r.Apply(Sample{
+			Collector: "basic", CloudWatchMetric: "NetworkReceiveThroughput", Statistic: "Average", Name: "aws_rds_network_receive_throughput_average",
+			Labels: labels, Value: 100, Timestamp: ts,
+		})
+		assert.True(t, applied)
+		require.Len(t, res, 2)
+		assert.Equal(t, Sample{
+			Collector: "basic", CloudWatchMetric: "NetworkReceiveThroughput", Statistic: "Average", Name: "aws_rds_network_receive_throughput_average",
+			Labels: labels, Value: 100, Timestamp: ts,
+		}, res[0])
+		assert.Equal(t, Sample{
+			Collector: "basic", CloudWatchMetric: "NetworkReceiveThroughput", Statistic: "Average", Name: "node_network_receive_bytes_total",
+			Labels: labels, Value: 0, Timestamp: ts, Counter: true,
+		}, res[1])
+
+		res, _ = r.Apply(Sample{
+			Collector: "basic", CloudWatchMetric: "NetworkReceiveThroughput", Statistic: "Average", Name: "aws_rds_network_receive_throughput_average",
+			Labels: labels, Value: 100, Timestamp: ts.Add(time.Minute),
+		})
+		require.Len(t, res, 2)
+		assert.Equal(t, 100.0, res[0].Value)
+		assert.Equal(t, 6000.0, res[1].Value)
+
+		s, applied = apply(t, r, Sample{Collector: "enhanced", Name: "node_network_receive_bytes_total", Labels: labels, Value: 10, Timestamp: ts})
+		assert.True(t, applied)
+		assert.True(t, s.Counter)
+		assert.Equal(t, 0.0, s.Value)
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		r := New([]config.Transform{
+			{Metric: "a", Scale: 2, Offset: 1, Rename: "b"},
+			{Metric: "b", Scale: 10},
+			{Metric: "a", Scale: 100}, // not applied after rename
+		})
+
+		s, applied := apply(t, r, Sample{Collector: "basic", Name: "a", Value: 3, Timestamp: ts})
+		assert.True(t, applied)
+		assert.Equal(t, Sample{Collector: "basic", Name: "b", Value: 70, Timestamp: ts}, s)
+	})
+
+	t.Run("Keep", func(t *testing.T) {
+		r := New([]config.Transform{
+			{Metric: "a", Scale: 2, Rename: "b", Keep: true},
+			{Metric: "a", Offset: 1},
+			{Metric: "b", Offset: 10},
+		})
+
+		res, applied := r.Apply(Sample{Collector: "basic", Name: "a", Labels: map[string]string{"instance": "db1"}, Value: 3})
+		assert.True(t, applied)
+		expected := []Sample{
+			{Collector: "basic", Name: "a", Labels: map[string]string{"instance": "db1"}, Value: 4},
+			{Collector: "basic", Name: "b", Labels: map[string]string{"instance": "db1"}, Value: 16},
+		}
+		assert.Equal(t, expected, res)
+	})
+
+	t.Run("RateToCounter", func(t *testing.T) {
+		r := New([]config.Transform{{Metric: "rate", RateToCounter: true, Rename: "total"}})
+
+		for _, step := range []struct {
+			labels   map[string]string
+			rate     float64
+			ts       time.Time
+			expected float64
+		}{
+			{map[string]string{"instance": "a"}, 10, ts, 0},
+			{map[string]string{"instance": "a"}, 5, ts.Add(time.Minute), 300},
+			{map[string]string{"instance": "b"}, 1, ts.Add(time.Minute), 0},
+			{map[string]string{"instance": "a"}, 5, ts.Add(time.Minute), 300}, // the same sample again
+			{map[string]string{"instance": "a"}, 1, ts.Add(90 * time.Second), 330},
+			{map[string]string{"instance": "b"}, 2, ts.Add(2 * time.Minute), 120},
+		} {
+			s, applied := apply(t, r, Sample{Collector: "enhanced", Name: "rate", Labels: step.labels, Value: step.rate, Timestamp: step.ts})
+			require.True(t, applied)
+			assert.Equal(t, "total", s.Name)
+			assert.True(t, s.Counter)
+			assert.Equal(t, step.expected, s.Value, "%+v", step)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var r *Rules
+		s, applied := apply(t, r, Sample{Collector: "basic", CloudWatchMetric: "EngineUptime", Name: "node_boot_time_seconds", Value: 60})
+		assert.True(t, applied)
+		assert.InDelta(t, float64(time.Now().Unix()-60), s.Value, 1)
+	})
+}